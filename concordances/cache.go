@@ -0,0 +1,304 @@
+package concordances
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	cacheHitsMetric        = "cache.hits"
+	cacheMissesMetric      = "cache.misses"
+	cacheEvictionsMetric   = "cache.evictions"
+	cacheExpirationsMetric = "cache.expirations"
+)
+
+// CachingDriver is a read-through cache in front of another Driver.
+// Results are cached per conceptId and per authority and identifierValue pair rather than per request,
+// so overlapping multi-identifier requests share entries and only the missing identifiers are read.
+// Concurrent requests missing the same identifier share a single read of it.
+type CachingDriver struct {
+	driver      Driver
+	cache       *lruCache
+	hits        metrics.Counter
+	misses      metrics.Counter
+	evictions   metrics.Counter
+	expirations metrics.Counter
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a read of the wrapped driver in progress for a cache key, done is closed once it completed
+type flight struct {
+	done         chan struct{}
+	concordances []Concordance
+	err          error
+}
+
+// NewCachingDriver wraps driver with a cache holding at most maxEntries identifiers, each for up to ttl.
+// Hits, misses, evictions of the least recently used entries beyond maxEntries and expirations of entries older
+// than ttl are counted in the given metrics registry.
+func NewCachingDriver(driver Driver, ttl time.Duration, maxEntries int, registry metrics.Registry) *CachingDriver {
+	cd := &CachingDriver{
+		driver:      driver,
+		hits:        metrics.GetOrRegisterCounter(cacheHitsMetric, registry),
+		misses:      metrics.GetOrRegisterCounter(cacheMissesMetric, registry),
+		evictions:   metrics.GetOrRegisterCounter(cacheEvictionsMetric, registry),
+		expirations: metrics.GetOrRegisterCounter(cacheExpirationsMetric, registry),
+		flights:     map[string]*flight{},
+	}
+	cd.cache = newLRUCache(ttl, maxEntries, func() { cd.evictions.Inc(1) }, func() { cd.expirations.Inc(1) })
+	return cd
+}
+
+// CheckConnectivity checks the connectivity of the wrapped driver
+func (cd *CachingDriver) CheckConnectivity(ctx context.Context) error {
+	return cd.driver.CheckConnectivity(ctx)
+}
+
+// ReadByConceptID caches the identifiers of every concept per set of authorities they are restricted to,
+// so that the wrapped driver only reads the identifiers of the requested authorities
+func (cd *CachingDriver) ReadByConceptID(ctx context.Context, ids []string, authorities []string) (concordances Concordances, found bool, err error) {
+	key := func(id string) string {
+		return conceptIDCacheKey(authorities, id)
+	}
+	return cd.read(ctx, ids, key, func(missing []string) (map[string][]Concordance, error) {
+		read, _, err := cd.driver.ReadByConceptID(ctx, missing, authorities)
+		if err != nil {
+			return nil, err
+		}
+		return matchInputs(missing, read.Concordance), nil
+	})
+}
+
+func (cd *CachingDriver) ReadByAuthority(ctx context.Context, authority string, ids []string) (concordances Concordances, found bool, err error) {
+	key := func(id string) string {
+		return authorityCacheKey(authority, id)
+	}
+	return cd.read(ctx, ids, key, func(missing []string) (map[string][]Concordance, error) {
+		read, _, err := cd.driver.ReadByAuthority(ctx, authority, missing)
+		if err != nil {
+			return nil, err
+		}
+		return matchInputs(missing, read.Concordance), nil
+	})
+}
+
+// ReadByAuthorities serves every group from the cache, reading the identifierValues missing from it per authority
+func (cd *CachingDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	var read [][]Concordance
+	for _, group := range groups {
+		groupConcordances, _, err := cd.ReadByAuthority(ctx, group.Authority, group.IdentifierValues)
+		if err != nil {
+			return Concordances{}, false, err
+		}
+		read = append(read, groupConcordances.Concordance)
+	}
+
+	merged := mergeConcordances(read...)
+	if len(merged) == 0 {
+		return Concordances{}, false, nil
+	}
+	return Concordances{Concordance: merged}, true, nil
+}
+
+// Translate is not cached, translations are read from the wrapped driver every time
+func (cd *CachingDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error) {
+	return cd.driver.Translate(ctx, from, to, identifierValues)
+}
+
+// ReadPage is not cached, pages are read from the wrapped driver every time
+func (cd *CachingDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	return cd.driver.ReadPage(ctx, lookup, page)
+}
+
+// ExportPage is not cached, exports are read from the wrapped driver every time
+func (cd *CachingDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error) {
+	return cd.driver.ExportPage(ctx, authority, after, limit)
+}
+
+// read serves the ids from the cache and reads the ones missing from it in a single call to the wrapped driver.
+// The ids already being read for another request are waited for rather than read again, and read again only if
+// that read failed.
+func (cd *CachingDriver) read(ctx context.Context, ids []string, key func(string) string, readMissing func([]string) (map[string][]Concordance, error)) (Concordances, bool, error) {
+	var groups [][]Concordance
+	var missing []string
+	var claimed []*flight
+	var waitedIDs []string
+	var waited []*flight
+	cd.mu.Lock()
+	for _, id := range ids {
+		k := key(id)
+		if cached, hit := cd.cache.get(k); hit {
+			groups = append(groups, cached)
+			continue
+		}
+		if f, inFlight := cd.flights[k]; inFlight {
+			waitedIDs, waited = append(waitedIDs, id), append(waited, f)
+			continue
+		}
+		f := &flight{done: make(chan struct{})}
+		cd.flights[k] = f
+		missing, claimed = append(missing, id), append(claimed, f)
+	}
+	cd.mu.Unlock()
+	cd.hits.Inc(int64(len(groups)))
+	cd.misses.Inc(int64(len(missing) + len(waited)))
+
+	if len(missing) > 0 {
+		read, err := readMissing(missing)
+		cd.land(missing, claimed, key, read, err)
+		if err != nil {
+			return Concordances{}, false, err
+		}
+		for _, id := range missing {
+			groups = append(groups, read[id])
+		}
+	}
+
+	var failed []string
+	for i, f := range waited {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return Concordances{}, false, ctx.Err()
+		}
+		if f.err != nil {
+			failed = append(failed, waitedIDs[i])
+			continue
+		}
+		groups = append(groups, f.concordances)
+	}
+	if len(failed) > 0 {
+		read, err := readMissing(failed)
+		if err != nil {
+			return Concordances{}, false, err
+		}
+		for _, id := range failed {
+			cd.cache.set(key(id), read[id])
+			groups = append(groups, read[id])
+		}
+	}
+
+	concordances := mergeConcordances(groups...)
+	if len(concordances) == 0 {
+		return Concordances{}, false, nil
+	}
+	return Concordances{Concordance: concordances}, true, nil
+}
+
+// land completes the flights of the ids read, caching what was read unless the read failed
+func (cd *CachingDriver) land(ids []string, flights []*flight, key func(string) string, read map[string][]Concordance, err error) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	for i, id := range ids {
+		k := key(id)
+		if err == nil {
+			cd.cache.set(k, read[id])
+		}
+		flights[i].concordances, flights[i].err = read[id], err
+		delete(cd.flights, k)
+		close(flights[i].done)
+	}
+}
+
+// conceptIDCacheKey holds the authorities in order and without duplicates, so that any order of them shares entries
+func conceptIDCacheKey(authorities []string, id string) string {
+	distinct := map[string]bool{}
+	for _, authority := range authorities {
+		distinct[authority] = true
+	}
+	sorted := make([]string, 0, len(distinct))
+	for authority := range distinct {
+		sorted = append(sorted, authority)
+	}
+	sort.Strings(sorted)
+	return "conceptId|" + strings.Join(sorted, ",") + "|" + id
+}
+
+func authorityCacheKey(authority string, identifierValue string) string {
+	return "authority|" + authority + "|" + identifierValue
+}
+
+type lruEntry struct {
+	key          string
+	concordances []Concordance
+	expires      time.Time
+}
+
+// lruCache is a size bound, least recently used cache whose entries expire after a fixed ttl
+type lruCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	onEvict    func()
+	onExpire   func()
+	now        func() time.Time
+}
+
+// newLRUCache calls onEvict whenever an entry is evicted to keep at most maxEntries, and onExpire whenever an entry
+// is found to be older than ttl
+func newLRUCache(ttl time.Duration, maxEntries int, onEvict func(), onExpire func()) *lruCache {
+	return &lruCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+		onEvict:    onEvict,
+		onExpire:   onExpire,
+		now:        time.Now,
+	}
+}
+
+func (c *lruCache) get(key string) ([]Concordance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if c.now().After(entry.expires) {
+		c.remove(el)
+		c.onExpire()
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.concordances, true
+}
+
+func (c *lruCache) set(key string, concordances []Concordance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{
+		key:          key,
+		concordances: append([]Concordance{}, concordances...),
+		expires:      c.now().Add(c.ttl),
+	}
+	if el, found := c.entries[key]; found {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		c.onEvict()
+	}
+}
+
+func (c *lruCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,303 @@
+package concordances
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	bankOfTestConcept      = Concept{ID: thingURL + "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}
+	bankOfTestFactset      = Concordance{Concept: bankOfTestConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}}
+	bankOfTestUPP          = Concordance{Concept: bankOfTestConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/UPP", IdentifierValue: "d56e7388-25cb-343e-aea9-8b512e28476e"}}
+	managedLocationConcept = Concept{ID: thingURL + "5aba454b-3e31-31b9-bdeb-0caf83f62b44", APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"}
+	managedLocationUPP     = Concordance{Concept: managedLocationConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/UPP", IdentifierValue: "5aba454b-3e31-31b9-bdeb-0caf83f62b44"}}
+)
+
+// foundFor returns the concordance as read for the requested conceptId UUID or identifierValue
+func foundFor(input string, c Concordance) Concordance {
+	c.Input = input
+	return c
+}
+
+// recordingDriver answers every lookup with the stored concordances and records the identifiers it was asked for
+type recordingDriver struct {
+	concordances []Concordance
+	requested    [][]string
+	filters      [][]string
+}
+
+func (d *recordingDriver) ReadByConceptID(ctx context.Context, ids []string, authorities []string) (Concordances, bool, error) {
+	d.requested = append(d.requested, ids)
+	d.filters = append(d.filters, authorities)
+	filtered := filterByAuthority(d.concordances, authorities)
+	return Concordances{Concordance: filtered}, len(filtered) > 0, nil
+}
+
+// filterByAuthority keeps the concordances of the given authority URIs, or all of them if there are none.
+func filterByAuthority(concordances []Concordance, authorities []string) []Concordance {
+	if len(authorities) == 0 {
+		return concordances
+	}
+
+	filtered := []Concordance{}
+	for _, c := range concordances {
+		for _, authority := range authorities {
+			if c.Identifier.Authority == authority {
+				filtered = append(filtered, c)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+func (d *recordingDriver) ReadByAuthority(ctx context.Context, authority string, ids []string) (Concordances, bool, error) {
+	d.requested = append(d.requested, ids)
+	return Concordances{Concordance: d.concordances}, len(d.concordances) > 0, nil
+}
+
+func (d *recordingDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (Concordances, bool, error) {
+	for _, group := range groups {
+		d.requested = append(d.requested, group.IdentifierValues)
+	}
+	return Concordances{Concordance: d.concordances}, len(d.concordances) > 0, nil
+}
+
+func (d *recordingDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) ([]Translation, bool, error) {
+	d.requested = append(d.requested, identifierValues)
+	return []Translation{}, false, nil
+}
+
+func (d *recordingDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (Concordances, bool, error) {
+	d.requested = append(d.requested, lookup.ConceptIDs)
+	return Concordances{Concordance: d.concordances}, len(d.concordances) > 0, nil
+}
+
+func (d *recordingDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (Concordances, string, error) {
+	d.requested = append(d.requested, []string{after})
+	return Concordances{Concordance: d.concordances}, "", nil
+}
+
+func (d *recordingDriver) CheckConnectivity(ctx context.Context) error {
+	return nil
+}
+
+func TestCachingDriverReadsOnlyMissingConceptIDs(t *testing.T) {
+	bankOfTestFactset := foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestFactset)
+	bankOfTestUPP := foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestUPP)
+	managedLocationUPP := foundFor("5aba454b-3e31-31b9-bdeb-0caf83f62b44", managedLocationUPP)
+	inner := &recordingDriver{concordances: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}}
+	registry := metrics.NewRegistry()
+	undertest := NewCachingDriver(inner, time.Minute, 10, registry)
+
+	conc, found, err := undertest.ReadByConceptID(context.Background(), []string{"d56e7388-25cb-343e-aea9-8b512e28476e"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.ElementsMatch(t, []Concordance{bankOfTestFactset, bankOfTestUPP}, conc.Concordance)
+
+	conc, found, err = undertest.ReadByConceptID(context.Background(), []string{"d56e7388-25cb-343e-aea9-8b512e28476e", "5aba454b-3e31-31b9-bdeb-0caf83f62b44"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.ElementsMatch(t, []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}, conc.Concordance)
+
+	assert.Equal(t, [][]string{{"d56e7388-25cb-343e-aea9-8b512e28476e"}, {"5aba454b-3e31-31b9-bdeb-0caf83f62b44"}}, inner.requested)
+	assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(cacheHitsMetric, registry).Count())
+	assert.EqualValues(t, 2, metrics.GetOrRegisterCounter(cacheMissesMetric, registry).Count())
+}
+
+func TestCachingDriverCachesIdentifierValuesPerAuthority(t *testing.T) {
+	bankOfTestFactset := foundFor("7IV872-E", bankOfTestFactset)
+	inner := &recordingDriver{concordances: []Concordance{bankOfTestFactset}}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		conc, found, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []Concordance{bankOfTestFactset}, conc.Concordance)
+	}
+	_, _, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FT-TME", []string{"7IV872-E"})
+	assert.NoError(t, err)
+
+	assert.Len(t, inner.requested, 2)
+}
+
+func TestCachingDriverCachesEveryAuthorityGroup(t *testing.T) {
+	bankOfTestFactset := foundFor("7IV872-E", bankOfTestFactset)
+	inner := &recordingDriver{concordances: []Concordance{bankOfTestFactset}}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+	groups := []AuthorityIdentifiers{
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValues: []string{"7IV872-E"}},
+		{Authority: "http://api.ft.com/system/LEI", IdentifierValues: []string{"VNF516RB4DFV5NQ22UF0"}},
+	}
+
+	for i := 0; i < 2; i++ {
+		conc, found, err := undertest.ReadByAuthorities(context.Background(), groups)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []Concordance{bankOfTestFactset}, conc.Concordance)
+	}
+	assert.Equal(t, [][]string{{"7IV872-E"}, {"VNF516RB4DFV5NQ22UF0"}}, inner.requested)
+}
+
+func TestCachingDriverCachesMisses(t *testing.T) {
+	inner := &recordingDriver{}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		conc, found, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"unknown"})
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Empty(t, conc.Concordance)
+	}
+	assert.Len(t, inner.requested, 1)
+}
+
+func TestCachingDriverExpiresEntries(t *testing.T) {
+	inner := &recordingDriver{concordances: []Concordance{bankOfTestFactset}}
+	registry := metrics.NewRegistry()
+	undertest := NewCachingDriver(inner, time.Minute, 10, registry)
+	now := time.Now()
+	undertest.cache.now = func() time.Time { return now }
+
+	_, _, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+	assert.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, _, err = undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+	assert.NoError(t, err)
+
+	assert.Len(t, inner.requested, 2)
+	assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(cacheExpirationsMetric, registry).Count())
+	assert.Zero(t, metrics.GetOrRegisterCounter(cacheEvictionsMetric, registry).Count())
+}
+
+func TestCachingDriverEvictsLeastRecentlyUsed(t *testing.T) {
+	inner := &recordingDriver{}
+	registry := metrics.NewRegistry()
+	undertest := NewCachingDriver(inner, time.Minute, 2, registry)
+
+	for _, ids := range [][]string{{"a"}, {"b"}, {"a"}, {"c"}, {"a"}, {"b"}} {
+		_, _, err := undertest.ReadByConceptID(context.Background(), ids, nil)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}, {"b"}}, inner.requested)
+	assert.EqualValues(t, 2, metrics.GetOrRegisterCounter(cacheEvictionsMetric, registry).Count())
+	assert.Zero(t, metrics.GetOrRegisterCounter(cacheExpirationsMetric, registry).Count())
+}
+
+// blockingDriver signals every ReadByAuthority on started and answers it once released, failing the first one with
+// failFirst if set
+type blockingDriver struct {
+	*recordingDriver
+	started   chan struct{}
+	release   chan struct{}
+	failFirst error
+}
+
+func (d *blockingDriver) ReadByAuthority(ctx context.Context, authority string, ids []string) (Concordances, bool, error) {
+	d.started <- struct{}{}
+	<-d.release
+	if err := d.failFirst; err != nil {
+		d.failFirst = nil
+		return Concordances{}, false, err
+	}
+	return d.recordingDriver.ReadByAuthority(ctx, authority, ids)
+}
+
+func TestCachingDriverSharesConcurrentReadsOfAnIdentifier(t *testing.T) {
+	inner := &blockingDriver{
+		recordingDriver: &recordingDriver{concordances: []Concordance{foundFor("7IV872-E", bankOfTestFactset)}},
+		started:         make(chan struct{}, 2),
+		release:         make(chan struct{}),
+	}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+
+	var wg sync.WaitGroup
+	read := func() {
+		defer wg.Done()
+		conc, found, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Len(t, conc.Concordance, 1)
+	}
+	wg.Add(2)
+	go read()
+	<-inner.started
+	go read()
+	time.Sleep(20 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.Len(t, inner.started, 0)
+	assert.Equal(t, [][]string{{"7IV872-E"}}, inner.requested)
+}
+
+func TestCachingDriverReadsAgainWhenTheSharedReadFailed(t *testing.T) {
+	inner := &blockingDriver{
+		recordingDriver: &recordingDriver{},
+		started:         make(chan struct{}, 2),
+		release:         make(chan struct{}),
+		failFirst:       context.Canceled,
+	}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+		assert.ErrorIs(t, err, context.Canceled)
+	}()
+	<-inner.started
+
+	done := make(chan error)
+	go func() {
+		_, _, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, [][]string{{"7IV872-E"}}, inner.requested)
+}
+
+func TestCachingDriverCachesConceptsPerAuthorityFilter(t *testing.T) {
+	bankOfTestFactset := foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestFactset)
+	bankOfTestUPP := foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestUPP)
+	inner := &recordingDriver{concordances: []Concordance{bankOfTestFactset, bankOfTestUPP}}
+	undertest := NewCachingDriver(inner, time.Minute, 10, metrics.NewRegistry())
+	ids := []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}
+
+	conc, found, err := undertest.ReadByConceptID(context.Background(), ids, []string{"http://api.ft.com/system/FACTSET"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []Concordance{bankOfTestFactset}, conc.Concordance)
+
+	conc, found, err = undertest.ReadByConceptID(context.Background(), ids, []string{"http://api.ft.com/system/LEI"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, conc.Concordance)
+
+	for _, authorities := range [][]string{
+		{"http://api.ft.com/system/UPP", "http://api.ft.com/system/FACTSET"},
+		{"http://api.ft.com/system/FACTSET", "http://api.ft.com/system/UPP", "http://api.ft.com/system/FACTSET"},
+	} {
+		conc, _, err = undertest.ReadByConceptID(context.Background(), ids, authorities)
+		assert.NoError(t, err)
+		assert.Equal(t, []Concordance{bankOfTestFactset, bankOfTestUPP}, conc.Concordance)
+	}
+
+	assert.Equal(t, [][]string{
+		{"http://api.ft.com/system/FACTSET"},
+		{"http://api.ft.com/system/LEI"},
+		{"http://api.ft.com/system/UPP", "http://api.ft.com/system/FACTSET"},
+	}, inner.filters, "the authorities should be passed to the wrapped driver, any order of them read only once")
+}
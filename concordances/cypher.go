@@ -1,229 +1,290 @@
 package concordances
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
+	"time"
 
 	ontology "github.com/Financial-Times/cm-graph-ontology/v2"
 	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+	"github.com/rcrowley/go-metrics"
 )
 
-const thingURL = "http://api.ft.com/things/"
+const (
+	thingURL = "http://api.ft.com/things/"
+
+	neo4jQueriesMetric          = "neo4j.queries"
+	neo4jAbandonedQueriesMetric = "neo4j.queries.abandoned"
+	neo4jRejectedQueriesMetric  = "neo4j.queries.rejected"
+
+	// maxInFlightQueries bounds the queries running against Neo4j, including the ones abandoned once their deadline
+	// passed, to the default connection pool size of the Neo4j driver
+	maxInFlightQueries = 100
+)
 
 // Driver interface
 type Driver interface {
-	ReadByConceptID(ids []string) (concordances Concordances, found bool, err error)
-	ReadByAuthority(authority string, ids []string) (concordances Concordances, found bool, err error)
-	CheckConnectivity() error
+	ReadByConceptID(ctx context.Context, ids []string, authorities []string) (concordances Concordances, found bool, err error)
+	ReadByAuthority(ctx context.Context, authority string, ids []string) (concordances Concordances, found bool, err error)
+	ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error)
+	Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error)
+	ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error)
+	ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error)
+	CheckConnectivity(ctx context.Context) error
+}
+
+// neoDriver is the part of cmneo4j.Driver used by CypherDriver
+type neoDriver interface {
+	Read(queries ...*cmneo4j.Query) error
+	VerifyConnectivity() error
 }
 
 // CypherDriver struct
 type CypherDriver struct {
-	driver       *cmneo4j.Driver
+	driver       neoDriver
 	publicAPIURL string
+	queryTimeout time.Duration
+	queryCount   metrics.Counter
+	abandoned    metrics.Counter
+	rejected     metrics.Counter
+	inFlight     chan struct{}
+	resolvers    *ResolverRegistry
 }
 
-// NewCypherDriver instantiate driver
-func NewCypherDriver(driver *cmneo4j.Driver, publicAPIURL string) (CypherDriver, error) {
+// NewCypherDriver instantiate driver, queryTimeout bounds every single Neo4j query and is ignored when not positive.
+// Every query executed against Neo4j is counted in the given metrics registry, along with the ones abandoned and the
+// ones rejected without being executed as too many queries were running.
+func NewCypherDriver(driver *cmneo4j.Driver, publicAPIURL string, queryTimeout time.Duration, registry metrics.Registry) (CypherDriver, error) {
+	return newCypherDriver(driver, publicAPIURL, queryTimeout, registry)
+}
+
+func newCypherDriver(driver neoDriver, publicAPIURL string, queryTimeout time.Duration, registry metrics.Registry) (CypherDriver, error) {
 	_, err := url.ParseRequestURI(publicAPIURL)
 	if err != nil {
 		return CypherDriver{}, err
 	}
 
-	return CypherDriver{driver, publicAPIURL}, nil
+	return CypherDriver{
+		driver:       driver,
+		publicAPIURL: publicAPIURL,
+		queryTimeout: queryTimeout,
+		queryCount:   metrics.GetOrRegisterCounter(neo4jQueriesMetric, registry),
+		abandoned:    metrics.GetOrRegisterCounter(neo4jAbandonedQueriesMetric, registry),
+		rejected:     metrics.GetOrRegisterCounter(neo4jRejectedQueriesMetric, registry),
+		inFlight:     make(chan struct{}, maxInFlightQueries),
+		resolvers:    defaultResolvers,
+	}, nil
 }
 
 // CheckConnectivity tests neo4j by running a simple cypher query
-func (cd CypherDriver) CheckConnectivity() error {
-	return cd.driver.VerifyConnectivity()
+func (cd CypherDriver) CheckConnectivity(ctx context.Context) error {
+	return cd.withDeadline(ctx, cd.driver.VerifyConnectivity)
 }
 
-func (cd CypherDriver) ReadByConceptID(identifiers []string) (concordances Concordances, found bool, err error) {
-	var results []neoReadStruct
-	query := &cmneo4j.Query{
-		Cypher: `
-		MATCH (p:Thing)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		MATCH (canonical)<-[:EQUIVALENT_TO]-(leafNode:Thing)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, leafNode.authority as authority, leafNode.authorityValue as authorityValue
-		UNION ALL
-
-		MATCH (p:Thing)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		WHERE exists(canonical.leiCode)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, 'LEI' as authority, canonical.leiCode as authorityValue
-		UNION ALL
-
-		MATCH (p:Location)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		WHERE exists(canonical.iso31661)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, 'ISO-3166-1' as authority, canonical.iso31661 as authorityValue
-		UNION ALL
-
-		MATCH (p:NAICSIndustryClassification)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		WHERE exists(canonical.industryIdentifier)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, 'NAICS' as authority, canonical.industryIdentifier as authorityValue
-		UNION ALL
-
-		MATCH (p:FTAnIIndustryClassification)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		WHERE exists(canonical.industryIdentifier)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, 'FTAnI' as authority, canonical.industryIdentifier as authorityValue
-		UNION ALL
-
-		MATCH (p:Thing)
-		WHERE p.uuid in $identifiers
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		MATCH (canonical)<-[:EQUIVALENT_TO]-(leafNode:Thing)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, 'UPP' as authority, leafNode.uuid as authorityValue
-        `,
-		Params: map[string]interface{}{"identifiers": identifiers},
-		Result: &results,
+// read runs the query against Neo4j in a span described by s, returning early with the context error once ctx is done
+// or the query timeout is hit.
+func (cd CypherDriver) read(ctx context.Context, s querySpan, q *cmneo4j.Query) (err error) {
+	ctx, span := startQuerySpan(ctx, s)
+	defer func() { endQuerySpan(span, q, err) }()
+	return cd.withDeadline(ctx, func() error {
+		cd.queryCount.Inc(1)
+		return cd.driver.Read(q)
+	})
+}
+
+// withDeadline runs op and stops waiting for it when ctx is done or the query timeout elapses.
+// cmneo4j accepts neither a context nor a transaction timeout, so an abandoned call runs until Neo4j completes it or
+// its dbms.transaction.timeout stops it, and its result is discarded. Abandoned calls keep their in-flight slot until
+// then, so that a slow Neo4j cannot pile up queries: once every slot is taken, op is not run, it is counted as
+// rejected and the context error is returned when the deadline passes.
+func (cd CypherDriver) withDeadline(ctx context.Context, op func() error) error {
+	if cd.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cd.queryTimeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	err = cd.driver.Read(query)
+	select {
+	case cd.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		cd.rejected.Inc(1)
+		return ctx.Err()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-cd.inFlight }()
+		done <- op()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		cd.abandoned.Inc(1)
+		return ctx.Err()
+	}
+}
 
-	if errors.Is(err, cmneo4j.ErrNoResultsFound) {
+// ReadByConceptID reads the identifiers of the concepts, restricted to the given authority URIs unless there are none.
+// Only the queries of the requested authorities are executed.
+func (cd CypherDriver) ReadByConceptID(ctx context.Context, identifiers []string, authorities []string) (concordances Concordances, found bool, err error) {
+	var results []neoReadStruct
+	query := cd.resolvers.conceptIDQuery(identifiers, authorities)
+	if query == nil {
 		return Concordances{}, false, nil
 	}
+	query.Result = &results
+
+	span := querySpan{name: "concordances.conceptId", authorities: authorities, identifiers: len(identifiers)}
+	concordances, found, err = cd.readConcordances(ctx, span, query, &results)
 	if err != nil {
 		return Concordances{}, false, fmt.Errorf("error accessing Concordance datastore for identifier %v: %w", identifiers, err)
 	}
+	return concordances, found, nil
+}
 
-	concordances = Concordances{
-		Concordance: []Concordance{},
+func (cd CypherDriver) ReadByAuthority(ctx context.Context, authority string, identifierValues []string) (concordances Concordances, found bool, err error) {
+	var results []neoReadStruct
+	query := cd.resolvers.authorityQuery(authority, identifierValues)
+	if query == nil {
+		return Concordances{}, false, nil
 	}
+	query.Result = &results
 
-	return processCypherQueryToConcordances(cd, query, results)
-
+	span := querySpan{name: "concordances.authority", authorities: []string{authority}, identifiers: len(identifierValues)}
+	concordances, found, err = cd.readConcordances(ctx, span, query, &results)
+	if err != nil {
+		return Concordances{}, false, fmt.Errorf("error accessing Concordance datastore for authorityValue %v: %w", identifierValues, err)
+	}
+	return concordances, found, nil
 }
 
-func (cd CypherDriver) ReadByAuthority(authority string, identifierValues []string) (concordances Concordances, found bool, err error) {
+// Translate reads the identifiers of the to authority of the concepts identified by the identifierValues of the from
+// authority, in a single query
+func (cd CypherDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error) {
 	var results []neoReadStruct
+	query := cd.resolvers.translateQuery(from, to, identifierValues)
+	if query == nil {
+		return []Translation{}, false, nil
+	}
+	query.Result = &results
 
-	authorityProperty, found := AuthorityFromURI(authority)
-	if !found {
-		return Concordances{}, false, nil
+	span := querySpan{name: "concordances.translate", authorities: []string{from, to}, identifiers: len(identifierValues)}
+	err = cd.read(ctx, span, query)
+	if errors.Is(err, cmneo4j.ErrNoResultsFound) {
+		return []Translation{}, false, nil
+	}
+	if err != nil {
+		return []Translation{}, false, fmt.Errorf("error accessing Concordance datastore for authorityValue %v: %w", identifierValues, err)
 	}
 
-	var query *cmneo4j.Query
-
-	if authorityProperty == "UPP" {
-		// We need to treat the UPP authority slightly different as it's stored elsewhere.
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (p:Thing)
-		WHERE p.uuid IN $authorityValue
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, p.uuid as UUID, 'UPP' as authority, p.uuid as authorityValue`,
-
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-			},
-			Result: &results,
-		}
-	} else if authorityProperty == "LEI" {
-		// We've gotta treat LEI special like as well.
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (p:Concept)
-		WHERE p.leiCode IN $authorityValue
-		AND exists(p.prefUUID)
-		RETURN DISTINCT p.prefUUID AS canonicalUUID, labels(p) AS types, p.uuid as UUID, 'LEI' as authority, p.leiCode as authorityValue`,
-
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-			},
-			Result: &results,
-		}
-	} else if authorityProperty == "ISO-3166-1" {
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (canonical:Location)
-		WHERE canonical.iso31661 IN $authorityValue
-		AND exists(canonical.prefUUID)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.uuid as UUID, 'ISO-3166-1' as authority, canonical.iso31661 as authorityValue
-			`,
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-			},
-			Result: &results,
-		}
-	} else if authorityProperty == "NAICS" {
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (canonical:NAICSIndustryClassification)
-		WHERE canonical.industryIdentifier IN $authorityValue
-		AND exists(canonical.prefUUID)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.uuid as UUID, 'NAICS' as authority, canonical.industryIdentifier as authorityValue
-			`,
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-			},
-			Result: &results,
+	translations = []Translation{}
+	for _, row := range results {
+		concept, err := neoConcept(row, cd.publicAPIURL)
+		if err != nil {
+			return []Translation{}, false, fmt.Errorf("transforming result from datastore: %w", err)
 		}
-	} else if authorityProperty == "FTAnI" {
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (canonical:FTAnIIndustryClassification)
-		WHERE canonical.industryIdentifier IN $authorityValue
-		AND exists(canonical.prefUUID)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.uuid as UUID, 'FTAnI' as authority, canonical.industryIdentifier as authorityValue
-			`,
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-			},
-			Result: &results,
+		translations = append(translations, Translation{
+			From:    Identifier{Authority: from, IdentifierValue: row.SourceValue},
+			To:      Identifier{Authority: to, IdentifierValue: row.AuthorityValue},
+			Concept: concept,
+		})
+	}
+	return translations, len(translations) > 0, nil
+}
+
+// ReadByAuthorities runs the authority specific query of every group and merges their results
+func (cd CypherDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	var read [][]Concordance
+	for _, group := range groups {
+		groupConcordances, groupFound, err := cd.ReadByAuthority(ctx, group.Authority, group.IdentifierValues)
+		if err != nil {
+			return Concordances{}, false, err
 		}
-	} else {
-		query = &cmneo4j.Query{
-			Cypher: `
-		MATCH (p:Thing)
-		WHERE p.authority = $authority AND p.authorityValue IN $authorityValue
-		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
-		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, p.uuid as UUID, p.authority as authority, p.authorityValue as authorityValue`,
-
-			Params: map[string]interface{}{
-				"authorityValue": identifierValues,
-				"authority":      authorityProperty,
-			},
-			Result: &results,
+		if groupFound {
+			read = append(read, groupConcordances.Concordance)
 		}
 	}
 
-	err = cd.driver.Read(query)
-	if errors.Is(err, cmneo4j.ErrNoResultsFound) {
+	if len(read) == 0 {
 		return Concordances{}, false, nil
 	}
+	return Concordances{Concordance: mergeConcordances(read...)}, true, nil
+}
+
+// ReadPage reads a single page of the concordances of the lookup, in the order of canonical concept and identifier
+func (cd CypherDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	var results []neoReadStruct
+	query := cd.resolvers.pageQuery(lookup, page)
+	if query == nil {
+		return Concordances{}, false, nil
+	}
+	query.Result = &results
+
+	concordances, found, err = cd.readConcordances(ctx, lookupSpan(lookup), query, &results)
 	if err != nil {
-		return Concordances{}, false, fmt.Errorf("error accessing Concordance datastore for authorityValue %v: %w", identifierValues, err)
+		return Concordances{}, false, fmt.Errorf("error accessing Concordance datastore for page %+v: %w", page, err)
 	}
+	return concordances, found, nil
+}
 
-	concordances = Concordances{
-		Concordance: []Concordance{},
+// ExportPage reads the concordances of the first limit identifierValues of the authority greater than after,
+// in the order of identifierValue. next is the identifierValue the following page starts after, empty on the last page.
+// The concordances are not read for any requested identifier so they have no input.
+func (cd CypherDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error) {
+	var results []neoReadStruct
+	query := cd.resolvers.exportQuery(authority, after, limit)
+	if query == nil {
+		return Concordances{}, "", nil
 	}
+	query.Result = &results
 
-	return processCypherQueryToConcordances(cd, query, results)
+	span := querySpan{name: "concordances.export", authorities: []string{authority}, identifiers: limit}
+	concordances, _, err = cd.readConcordances(ctx, span, query, &results)
+	if err != nil {
+		return Concordances{}, "", fmt.Errorf("error exporting authority %s after %q: %w", authority, after, err)
+	}
+
+	concordances, next = exportedConcordances(concordances, limit)
+	return concordances, next, nil
 }
 
-func processCypherQueryToConcordances(cd CypherDriver, q *cmneo4j.Query, results []neoReadStruct) (concordances Concordances, found bool, err error) {
-	err = cd.driver.Read(q)
+// exportedConcordances strips the input of a page of exported concordances, ordered by identifierValue, and finds
+// the identifierValue the next page starts after if the page holds limit identifierValues
+func exportedConcordances(concordances Concordances, limit int) (exported Concordances, next string) {
+	exported = Concordances{Concordance: []Concordance{}}
+	values := map[string]bool{}
+	seen := map[Concordance]bool{}
+	for _, c := range concordances.Concordance {
+		values[c.Identifier.IdentifierValue] = true
+		c.Input, c.InputType = "", ""
+		if !seen[c] {
+			seen[c] = true
+			exported.Concordance = append(exported.Concordance, c)
+		}
+	}
+	if limit > 0 && len(values) == limit {
+		next = exported.Concordance[len(exported.Concordance)-1].Identifier.IdentifierValue
+	}
+	return exported, next
+}
+
+// readConcordances executes the query exactly once and transforms the rows read into results
+func (cd CypherDriver) readConcordances(ctx context.Context, s querySpan, q *cmneo4j.Query, results *[]neoReadStruct) (concordances Concordances, found bool, err error) {
+	err = cd.read(ctx, s, q)
 	if errors.Is(err, cmneo4j.ErrNoResultsFound) {
 		return Concordances{}, false, nil
 	}
-
 	if err != nil {
-		return Concordances{}, false, fmt.Errorf("error accessing Concordance datastore: %w", err)
+		return Concordances{}, false, err
 	}
 
-	concordances, err = neoReadStructToConcordances(results, cd.publicAPIURL)
+	concordances, err = neoReadStructToConcordances(*results, cd.publicAPIURL)
 	if err != nil {
 		return Concordances{}, false, fmt.Errorf("transforming result from datastore: %w", err)
 	}
@@ -235,29 +296,59 @@ func neoReadStructToConcordances(neo []neoReadStruct, baseURL string) (Concordan
 	concordances := Concordances{
 		Concordance: []Concordance{},
 	}
+	seen := map[Concordance]bool{}
 	for _, neoCon := range neo {
 		var con = Concordance{}
-		var concept = Concept{}
 
-		apiURL, err := ontology.APIURL(neoCon.CanonicalUUID, neoCon.Types, baseURL)
+		concept, err := neoConcept(neoCon, baseURL)
 		if err != nil {
-			return Concordances{}, fmt.Errorf("building APIURL for %q: %w", neoCon.CanonicalUUID, err)
+			return Concordances{}, err
 		}
-
-		concept.ID = thingIDURL(neoCon.CanonicalUUID)
-		concept.APIURL = apiURL
 		authorityURI, found := AuthorityToURI(neoCon.Authority)
 		if !found {
 			continue
 		}
 		con.Identifier = Identifier{Authority: authorityURI, IdentifierValue: neoCon.AuthorityValue}
+		con.Input = neoCon.Input
+		con.InputType = neoCon.InputType
 
 		con.Concept = concept
+		if seen[con] {
+			continue
+		}
+		seen[con] = true
 		concordances.Concordance = append(concordances.Concordance, con)
 	}
 	return concordances, nil
 }
 
+func neoConcept(neoCon neoReadStruct, baseURL string) (Concept, error) {
+	apiURL, err := ontology.APIURL(neoCon.CanonicalUUID, neoCon.Types, baseURL)
+	if err != nil {
+		return Concept{}, fmt.Errorf("building APIURL for %q: %w", neoCon.CanonicalUUID, err)
+	}
+
+	return Concept{
+		ID:           thingIDURL(neoCon.CanonicalUUID),
+		APIURL:       apiURL,
+		PrefLabel:    neoCon.PrefLabel,
+		Type:         mostSpecificType(neoCon.Types),
+		IsDeprecated: neoCon.IsDeprecated,
+	}, nil
+}
+
+// mostSpecificType is the most specific type of the labels in the ontology, empty if there is none
+func mostSpecificType(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	conceptType, err := ontology.MostSpecificType(labels)
+	if err != nil {
+		return ""
+	}
+	return conceptType
+}
+
 func AuthorityFromURI(uri string) (string, bool) {
 	for a, u := range ontology.GetConfig().GetSystemsURIMap() {
 		if u == uri {
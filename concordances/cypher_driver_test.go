@@ -0,0 +1,245 @@
+package concordances
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeNeoDriver struct {
+	executions int
+	queries    []*cmneo4j.Query
+	rows       []neoReadStruct
+	err        error
+	delay      time.Duration
+}
+
+func (f *fakeNeoDriver) Read(queries ...*cmneo4j.Query) error {
+	f.executions += len(queries)
+	f.queries = append(f.queries, queries...)
+	time.Sleep(f.delay)
+	if f.err != nil {
+		return f.err
+	}
+	for _, q := range queries {
+		*q.Result.(*[]neoReadStruct) = f.rows
+	}
+	return nil
+}
+
+func (f *fakeNeoDriver) VerifyConnectivity() error {
+	return f.err
+}
+
+var bankOfTestRows = []neoReadStruct{
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, Authority: "LEI", AuthorityValue: "VNF516RB4DFV5NQ22UF0"},
+}
+
+func TestCypherDriverExecutesEachLookupOnce(t *testing.T) {
+	tests := []struct {
+		name   string
+		lookup func(cd CypherDriver) (Concordances, bool, error)
+	}{
+		{
+			name: "ByConceptID",
+			lookup: func(cd CypherDriver) (Concordances, bool, error) {
+				return cd.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+			},
+		},
+		{
+			name: "ByAuthority",
+			lookup: func(cd CypherDriver) (Concordances, bool, error) {
+				return cd.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+			},
+		},
+		{
+			name: "ByLEIAuthority",
+			lookup: func(cd CypherDriver) (Concordances, bool, error) {
+				return cd.ReadByAuthority(context.Background(), "http://api.ft.com/system/LEI", []string{"VNF516RB4DFV5NQ22UF0"})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &fakeNeoDriver{rows: bankOfTestRows}
+			registry := metrics.NewRegistry()
+			undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, registry)
+			assert.NoError(t, err)
+
+			conc, found, err := test.lookup(undertest)
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Len(t, conc.Concordance, 2)
+			assert.Equal(t, 1, fake.executions)
+			assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(neo4jQueriesMetric, registry).Count())
+		})
+	}
+}
+
+func TestCypherDriverReturnsNotFoundWithoutError(t *testing.T) {
+	fake := &fakeNeoDriver{err: cmneo4j.ErrNoResultsFound}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	conc, found, err := undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, conc.Concordance)
+	assert.Equal(t, 1, fake.executions)
+}
+
+func TestCypherDriverStopsWaitingWhenQueryTimeoutIsHit(t *testing.T) {
+	fake := &fakeNeoDriver{rows: bankOfTestRows, delay: 200 * time.Millisecond}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 10*time.Millisecond, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	_, found, err := undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, found)
+}
+
+func TestCypherDriverBoundsAbandonedQueries(t *testing.T) {
+	fake := &fakeNeoDriver{rows: bankOfTestRows, delay: 200 * time.Millisecond}
+	registry := metrics.NewRegistry()
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 10*time.Millisecond, registry)
+	assert.NoError(t, err)
+	undertest.inFlight = make(chan struct{}, 1)
+
+	_, _, err = undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	// the abandoned query still holds the only slot, so this one is never run
+	_, _, err = undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(neo4jAbandonedQueriesMetric, registry).Count())
+	assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(neo4jRejectedQueriesMetric, registry).Count())
+	assert.EqualValues(t, 1, metrics.GetOrRegisterCounter(neo4jQueriesMetric, registry).Count(), "the rejected query should not be counted as executed")
+	assert.Len(t, undertest.inFlight, 1)
+}
+
+func TestCypherDriverRunsOneQueryPerAuthority(t *testing.T) {
+	fake := &fakeNeoDriver{rows: bankOfTestRows}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	conc, found, err := undertest.ReadByAuthorities(context.Background(), []AuthorityIdentifiers{
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValues: []string{"7IV872-E"}},
+		{Authority: "http://api.ft.com/system/LEI", IdentifierValues: []string{"VNF516RB4DFV5NQ22UF0"}},
+	})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, conc.Concordance, 2)
+	assert.Equal(t, 2, fake.executions)
+}
+
+func TestCypherDriverDoesNotQueryUnknownAuthorities(t *testing.T) {
+	fake := &fakeNeoDriver{rows: bankOfTestRows}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	_, found, err := undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, []string{"http://api.ft.com/system/UNKNOWN"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Zero(t, fake.executions)
+}
+
+func TestCypherDriverTranslatesInASingleQuery(t *testing.T) {
+	fake := &fakeNeoDriver{rows: []neoReadStruct{
+		{SourceValue: "7IV872-E", CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", IsDeprecated: true, Authority: "LEI", AuthorityValue: "VNF516RB4DFV5NQ22UF0"},
+	}}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	translations, found, err := undertest.Translate(context.Background(), "http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI", []string{"7IV872-E"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, fake.executions)
+	assert.Len(t, translations, 1)
+	assert.Equal(t, Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}, translations[0].From)
+	assert.Equal(t, Identifier{Authority: "http://api.ft.com/system/LEI", IdentifierValue: "VNF516RB4DFV5NQ22UF0"}, translations[0].To)
+	assert.Equal(t, thingURL+"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", translations[0].Concept.ID)
+	assert.True(t, translations[0].Concept.IsDeprecated)
+}
+
+func TestCypherDriverDoesNotTranslateUnknownAuthorities(t *testing.T) {
+	fake := &fakeNeoDriver{rows: bankOfTestRows}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	translations, found, err := undertest.Translate(context.Background(), "http://api.ft.com/system/FACTSET", "http://api.ft.com/system/UNKNOWN", []string{"7IV872-E"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, translations)
+	assert.Zero(t, fake.executions)
+}
+
+func TestCypherDriverReturnsInputAndDeprecation(t *testing.T) {
+	fake := &fakeNeoDriver{rows: []neoReadStruct{
+		{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, IsDeprecated: true, UUID: "2cdeb859-70df-3a0e-b125-f958366bea44", Input: "2cdeb859-70df-3a0e-b125-f958366bea44", InputType: InputTypeDeprecated, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+		{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, IsDeprecated: true, UUID: "d56e7388-25cb-343e-aea9-8b512e28476e", Input: "d56e7388-25cb-343e-aea9-8b512e28476e", InputType: InputTypeLeaf, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+		{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, IsDeprecated: true, UUID: "d56e7388-25cb-343e-aea9-8b512e28476e", Input: "d56e7388-25cb-343e-aea9-8b512e28476e", InputType: InputTypeLeaf, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+	}}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	conc, found, err := undertest.ReadByConceptID(context.Background(), []string{"2cdeb859-70df-3a0e-b125-f958366bea44", "d56e7388-25cb-343e-aea9-8b512e28476e"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, conc.Concordance, 2, "duplicate rows should be returned once")
+	assert.Equal(t, "2cdeb859-70df-3a0e-b125-f958366bea44", conc.Concordance[0].Input)
+	assert.Equal(t, InputTypeDeprecated, conc.Concordance[0].InputType)
+	assert.Equal(t, "d56e7388-25cb-343e-aea9-8b512e28476e", conc.Concordance[1].Input)
+	assert.Equal(t, InputTypeLeaf, conc.Concordance[1].InputType)
+	for _, c := range conc.Concordance {
+		assert.True(t, c.Concept.IsDeprecated)
+	}
+}
+
+func TestCypherDriverExportPageContinuesAfterTheLastIdentifierOfAFullPage(t *testing.T) {
+	fake := &fakeNeoDriver{rows: []neoReadStruct{
+		{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, Input: "7IV872-E", InputType: InputTypeLeaf, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+		{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, Input: "7IV872-E", InputType: InputTypeCanonical, Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+		{CanonicalUUID: "5aba454b-3e31-31b9-bdeb-0caf83f62b44", Types: []string{"Thing", "Concept", "Location"}, Input: "B000BB-S", InputType: InputTypeCanonical, Authority: "FACTSET", AuthorityValue: "B000BB-S"},
+	}}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	conc, next, err := undertest.ExportPage(context.Background(), "http://api.ft.com/system/FACTSET", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.executions)
+	assert.Equal(t, "B000BB-S", next)
+	assert.Len(t, conc.Concordance, 2, "rows differing only by input should be exported once")
+	for _, c := range conc.Concordance {
+		assert.Empty(t, c.Input)
+		assert.Empty(t, c.InputType)
+	}
+
+	_, next, err = undertest.ExportPage(context.Background(), "http://api.ft.com/system/FACTSET", "", 3)
+	assert.NoError(t, err)
+	assert.Empty(t, next, "a page of fewer identifiers than the limit is the last one")
+
+	_, next, err = undertest.ExportPage(context.Background(), "http://api.ft.com/system/UNKNOWN", "", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, 2, fake.executions)
+}
+
+func TestNeo4jConnectivityCheck(t *testing.T) {
+	fake := &fakeNeoDriver{}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+	check := Neo4jConnectivityCheck(undertest)
+
+	_, err = check.Checker()
+	assert.NoError(t, err)
+
+	fake.err = errors.New("connection refused")
+	_, err = check.Checker()
+	assert.EqualError(t, err, "connection refused")
+}
@@ -4,6 +4,7 @@
 package concordances
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -15,73 +16,74 @@ import (
 	"github.com/Financial-Times/cm-graph-ontology/v2/neo4j"
 	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
 	"github.com/Financial-Times/go-logger/v2"
+	"github.com/rcrowley/go-metrics"
 	"github.com/stretchr/testify/assert"
 )
 
 var concordedBrandSmartlogic = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/b20801ac-5a76-43cf-b816-8c3b2f7133ad",
 		APIURL: "http://api.ft.com/brands/b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/SMARTLOGIC",
 		IdentifierValue: "b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
 }
 
 var concordedManagedLocationByConceptId = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/WIKIDATA",
 				IdentifierValue: "http://www.wikidata.org/entity/Q218"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/FT-TME",
 				IdentifierValue: "TnN0ZWluX0dMX1JP-R0w="},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/MANAGEDLOCATION",
 				IdentifierValue: "5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/ISO-3166-1",
 				IdentifierValue: "RO"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "4534282c-d3ee-3595-9957-81a9293200f3"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "4411b761-e632-30e7-855c-06aeca76c48d"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
 		},
@@ -89,12 +91,12 @@ var concordedManagedLocationByConceptId = Concordances{
 }
 
 var concordedManagedLocationByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/MANAGEDLOCATION",
 				IdentifierValue: "5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
 		},
@@ -102,12 +104,12 @@ var concordedManagedLocationByAuthority = Concordances{
 }
 
 var concordedManagedLocationByISO31661Authority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
 				APIURL: "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/ISO-3166-1",
 				IdentifierValue: "RO"},
 		},
@@ -115,87 +117,87 @@ var concordedManagedLocationByISO31661Authority = Concordances{
 }
 
 var concordedBrandSmartlogicUPP = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/b20801ac-5a76-43cf-b816-8c3b2f7133ad",
 		APIURL: "http://api.ft.com/brands/b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/UPP",
 		IdentifierValue: "b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
 }
 
 var concordedBrandTME = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/b20801ac-5a76-43cf-b816-8c3b2f7133ad",
 		APIURL: "http://api.ft.com/brands/b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/FT-TME",
 		IdentifierValue: "VGhlIFJvbWFu-QnJhbmRz"},
 }
 
 var concordedBrandTMEUPP = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/b20801ac-5a76-43cf-b816-8c3b2f7133ad",
 		APIURL: "http://api.ft.com/brands/b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/UPP",
 		IdentifierValue: "70f4732b-7f7d-30a1-9c29-0cceec23760e"},
 }
 
 var expectedConcordanceBankOfTest = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "2cdeb859-70df-3a0e-b125-f958366bea44"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/FACTSET",
 				IdentifierValue: "7IV872-E"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/FT-TME",
 				IdentifierValue: "QmFuayBvZiBUZXN0-T04="},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/LEI",
 				IdentifierValue: "VNF516RB4DFV5NQ22UF0"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/SMARTLOGIC",
 				IdentifierValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "d56e7388-25cb-343e-aea9-8b512e28476e"},
 		},
@@ -203,12 +205,12 @@ var expectedConcordanceBankOfTest = Concordances{
 }
 
 var expectedConcordanceBankOfTestByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/FACTSET",
 				IdentifierValue: "7IV872-E"},
 		},
@@ -216,12 +218,12 @@ var expectedConcordanceBankOfTestByAuthority = Concordances{
 }
 
 var expectedConcordanceBankOfTestByUPPAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "d56e7388-25cb-343e-aea9-8b512e28476e"},
 		},
@@ -229,59 +231,143 @@ var expectedConcordanceBankOfTestByUPPAuthority = Concordances{
 }
 
 var expectedConcordanceBankOfTestByLEIAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
 				APIURL: "http://api.ft.com/organisations/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/LEI",
 				IdentifierValue: "VNF516RB4DFV5NQ22UF0"},
 		},
 	},
 }
 
+var expectedConcordanceBankOfTestEquityByISINAuthority = Concordances{
+	Concordance: []Concordance{
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/ISIN",
+				IdentifierValue: "GB00BTST1234"},
+		},
+	},
+}
+
+var expectedConcordanceBankOfTestEquityByFIGIAuthority = Concordances{
+	Concordance: []Concordance{
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/FIGI",
+				IdentifierValue: "BBG000BTST12"},
+		},
+	},
+}
+
+var expectedConcordanceBankOfTestEquityByTickerAuthority = Concordances{
+	Concordance: []Concordance{
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/TICKER",
+				IdentifierValue: "BOT"},
+		},
+	},
+}
+
+var expectedConcordanceBankOfTestEquity = Concordances{
+	Concordance: []Concordance{
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/FACTSET",
+				IdentifierValue: "BTST12-S"},
+		},
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/UPP",
+				IdentifierValue: "6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+		},
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/ISIN",
+				IdentifierValue: "GB00BTST1234"},
+		},
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/FIGI",
+				IdentifierValue: "BBG000BTST12"},
+		},
+		{
+			Concept: Concept{
+				ID:     "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
+				APIURL: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			Identifier: Identifier{
+				Authority:       "http://api.ft.com/system/TICKER",
+				IdentifierValue: "BOT"},
+		},
+	},
+}
+
 var unconcordedBrandTME = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/ad56856a-7d38-48e2-a131-7d104f17e8f6",
 		APIURL: "http://api.ft.com/brands/ad56856a-7d38-48e2-a131-7d104f17e8f6"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/FT-TME",
 		IdentifierValue: "UGFydHkgcGVvcGxl-QnJhbmRz"},
 }
 
 var unconcordedBrandTMEUPP = Concordance{
-	Concept{
+	Concept: Concept{
 		ID:     "http://api.ft.com/things/ad56856a-7d38-48e2-a131-7d104f17e8f6",
 		APIURL: "http://api.ft.com/brands/ad56856a-7d38-48e2-a131-7d104f17e8f6"},
-	Identifier{
+	Identifier: Identifier{
 		Authority:       "http://api.ft.com/system/UPP",
 		IdentifierValue: "ad56856a-7d38-48e2-a131-7d104f17e8f6"},
 }
 
 var expectedConcordanceNAICSIndustryClassification = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d",
 				APIURL: "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/SMARTLOGIC",
 				IdentifierValue: "38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d",
 				APIURL: "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/NAICS",
 				IdentifierValue: "5111"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d",
 				APIURL: "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
 		},
@@ -289,12 +375,12 @@ var expectedConcordanceNAICSIndustryClassification = Concordances{
 }
 
 var expectedConcordanceNAICSIndustryClassificationByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d",
 				APIURL: "http://api.ft.com/things/38ee195d-ebdd-48a9-af4b-c8a322e7b04d"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/NAICS",
 				IdentifierValue: "5111"},
 		},
@@ -302,20 +388,20 @@ var expectedConcordanceNAICSIndustryClassificationByAuthority = Concordances{
 }
 
 var expectedConcordanceSVProvision = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/1808c3fc-04bb-589b-a457-640bffa8f6c6",
 				APIURL: "http://api.ft.com/concepts/1808c3fc-04bb-589b-a457-640bffa8f6c6"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "1808c3fc-04bb-589b-a457-640bffa8f6c6"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/1808c3fc-04bb-589b-a457-640bffa8f6c6",
 				APIURL: "http://api.ft.com/concepts/1808c3fc-04bb-589b-a457-640bffa8f6c6"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/8e6c705e-1132-42a2-8db0-c295e29e8658",
 				IdentifierValue: "65d735ebad5f88460e919a42"},
 		},
@@ -323,12 +409,12 @@ var expectedConcordanceSVProvision = Concordances{
 }
 
 var expectedConcordanceSVProvisionByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/1808c3fc-04bb-589b-a457-640bffa8f6c6",
 				APIURL: "http://api.ft.com/concepts/1808c3fc-04bb-589b-a457-640bffa8f6c6"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/8e6c705e-1132-42a2-8db0-c295e29e8658",
 				IdentifierValue: "65d735ebad5f88460e919a42"},
 		},
@@ -336,20 +422,20 @@ var expectedConcordanceSVProvisionByAuthority = Concordances{
 }
 
 var expectedConcordanceFTPCGenre = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2",
 				APIURL: "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2",
 				APIURL: "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
 		},
@@ -357,12 +443,12 @@ var expectedConcordanceFTPCGenre = Concordances{
 }
 
 var expectedConcordanceFTPCGenreByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2",
 				APIURL: "http://api.ft.com/things/e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "e02fb4c0-1fe5-476b-b791-e921db5b99f2"},
 		},
@@ -370,20 +456,20 @@ var expectedConcordanceFTPCGenreByAuthority = Concordances{
 }
 
 var expectedConcordanceFTPCSource = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf",
 				APIURL: "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "8a852776-38b4-47fc-bb5e-e496801a28bf"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf",
 				APIURL: "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "8a852776-38b4-47fc-bb5e-e496801a28bf"},
 		},
@@ -391,12 +477,12 @@ var expectedConcordanceFTPCSource = Concordances{
 }
 
 var expectedConcordanceFTPCSourceByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf",
 				APIURL: "http://api.ft.com/things/8a852776-38b4-47fc-bb5e-e496801a28bf"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "8a852776-38b4-47fc-bb5e-e496801a28bf"},
 		},
@@ -404,20 +490,20 @@ var expectedConcordanceFTPCSourceByAuthority = Concordances{
 }
 
 var expectedConcordanceFTPCAssetType = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926",
 				APIURL: "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "c5440e5e-a472-4948-ab33-97e0089dd926"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926",
 				APIURL: "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "c5440e5e-a472-4948-ab33-97e0089dd926"},
 		},
@@ -425,12 +511,12 @@ var expectedConcordanceFTPCAssetType = Concordances{
 }
 
 var expectedConcordanceFTPCAssetTypeByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926",
 				APIURL: "http://api.ft.com/things/c5440e5e-a472-4948-ab33-97e0089dd926"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/724b5e36-6d45-4cf1-b1c2-3f676b21f21b",
 				IdentifierValue: "c5440e5e-a472-4948-ab33-97e0089dd926"},
 		},
@@ -438,20 +524,20 @@ var expectedConcordanceFTPCAssetTypeByAuthority = Concordances{
 }
 
 var expectedConcordanceFTAOrganisationDetails = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/77701984-3542-4f77-91aa-b5f7bfa43330",
 				APIURL: "http://api.ft.com/concepts/77701984-3542-4f77-91aa-b5f7bfa43330"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "77701984-3542-4f77-91aa-b5f7bfa43330"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/77701984-3542-4f77-91aa-b5f7bfa43330",
 				APIURL: "http://api.ft.com/concepts/77701984-3542-4f77-91aa-b5f7bfa43330"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/19d50190-8656-4e91-8d34-82e646ada9c9",
 				IdentifierValue: "77701984-3542-4f77-91aa-b5f7bfa43330"},
 		},
@@ -459,12 +545,12 @@ var expectedConcordanceFTAOrganisationDetails = Concordances{
 }
 
 var expectedConcordanceFTAOrganisationDetailsByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/77701984-3542-4f77-91aa-b5f7bfa43330",
 				APIURL: "http://api.ft.com/concepts/77701984-3542-4f77-91aa-b5f7bfa43330"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/19d50190-8656-4e91-8d34-82e646ada9c9",
 				IdentifierValue: "77701984-3542-4f77-91aa-b5f7bfa43330"},
 		},
@@ -472,20 +558,20 @@ var expectedConcordanceFTAOrganisationDetailsByAuthority = Concordances{
 }
 
 var expectedConcordanceFTAPersonDetails = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/a671f5a9-b9a4-4836-a174-fc273166f0db",
 				APIURL: "http://api.ft.com/concepts/a671f5a9-b9a4-4836-a174-fc273166f0db"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "a671f5a9-b9a4-4836-a174-fc273166f0db"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/a671f5a9-b9a4-4836-a174-fc273166f0db",
 				APIURL: "http://api.ft.com/concepts/a671f5a9-b9a4-4836-a174-fc273166f0db"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/19d50190-8656-4e91-8d34-82e646ada9c9",
 				IdentifierValue: "a671f5a9-b9a4-4836-a174-fc273166f0db"},
 		},
@@ -493,12 +579,12 @@ var expectedConcordanceFTAPersonDetails = Concordances{
 }
 
 var expectedConcordanceFTAPersonDetailsByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/a671f5a9-b9a4-4836-a174-fc273166f0db",
 				APIURL: "http://api.ft.com/concepts/a671f5a9-b9a4-4836-a174-fc273166f0db"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/19d50190-8656-4e91-8d34-82e646ada9c9",
 				IdentifierValue: "a671f5a9-b9a4-4836-a174-fc273166f0db"},
 		},
@@ -506,20 +592,20 @@ var expectedConcordanceFTAPersonDetailsByAuthority = Concordances{
 }
 
 var expectedConcordanceSVCategory = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366",
 				APIURL: "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366",
 				APIURL: "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/8e6c705e-1132-42a2-8db0-c295e29e8658",
 				IdentifierValue: "e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
 		},
@@ -527,12 +613,12 @@ var expectedConcordanceSVCategory = Concordances{
 }
 
 var expectedConcordanceSVCategoryByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366",
 				APIURL: "http://api.ft.com/things/e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/8e6c705e-1132-42a2-8db0-c295e29e8658",
 				IdentifierValue: "e0fc58d1-8dc5-47c6-90b1-59ccf8217366"},
 		},
@@ -540,20 +626,20 @@ var expectedConcordanceSVCategoryByAuthority = Concordances{
 }
 
 var expectedConcordancePersonGeneric = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/3c4666ef-b403-4313-b648-d639762750e4",
 				APIURL: "http://api.ft.com/people/3c4666ef-b403-4313-b648-d639762750e4"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/UPP",
 				IdentifierValue: "3c4666ef-b403-4313-b648-d639762750e4"},
 		},
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/3c4666ef-b403-4313-b648-d639762750e4",
 				APIURL: "http://api.ft.com/people/3c4666ef-b403-4313-b648-d639762750e4"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/GENERIC",
 				IdentifierValue: "3c4666ef-b403-4313-b648-d639762750e4"},
 		},
@@ -561,12 +647,12 @@ var expectedConcordancePersonGeneric = Concordances{
 }
 
 var expectedConcordancePersonGenericByAuthority = Concordances{
-	[]Concordance{
+	Concordance: []Concordance{
 		{
-			Concept{
+			Concept: Concept{
 				ID:     "http://api.ft.com/things/3c4666ef-b403-4313-b648-d639762750e4",
 				APIURL: "http://api.ft.com/people/3c4666ef-b403-4313-b648-d639762750e4"},
-			Identifier{
+			Identifier: Identifier{
 				Authority:       "http://api.ft.com/system/GENERIC",
 				IdentifierValue: "3c4666ef-b403-4313-b648-d639762750e4"},
 		},
@@ -580,6 +666,7 @@ func TestNeoReadByConceptID(t *testing.T) {
 		name        string
 		fixture     string
 		conceptIDs  []string
+		authorities []string
 		expectedLen int
 		expected    Concordances
 	}{
@@ -588,14 +675,22 @@ func TestNeoReadByConceptID(t *testing.T) {
 			fixture:     "Brand-Unconcorded-ad56856a-7d38-48e2-a131-7d104f17e8f6.json",
 			conceptIDs:  []string{"ad56856a-7d38-48e2-a131-7d104f17e8f6"},
 			expectedLen: 2,
-			expected:    Concordances{[]Concordance{unconcordedBrandTME, unconcordedBrandTMEUPP}},
+			expected:    Concordances{Concordance: []Concordance{unconcordedBrandTME, unconcordedBrandTMEUPP}},
 		},
 		{
 			name:        "NewModel_Concorded",
 			fixture:     "Brand-Concorded-b20801ac-5a76-43cf-b816-8c3b2f7133ad.json",
 			conceptIDs:  []string{"b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
 			expectedLen: 4,
-			expected:    Concordances{[]Concordance{concordedBrandSmartlogic, concordedBrandSmartlogicUPP, concordedBrandTME, concordedBrandTMEUPP}},
+			expected:    Concordances{Concordance: []Concordance{concordedBrandSmartlogic, concordedBrandSmartlogicUPP, concordedBrandTME, concordedBrandTMEUPP}},
+		},
+		{
+			name:        "NewModel_Concorded_FilteredByAuthority",
+			fixture:     "Brand-Concorded-b20801ac-5a76-43cf-b816-8c3b2f7133ad.json",
+			conceptIDs:  []string{"b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
+			authorities: []string{"http://api.ft.com/system/SMARTLOGIC"},
+			expectedLen: 1,
+			expected:    Concordances{Concordance: []Concordance{concordedBrandSmartlogic}},
 		},
 		{
 			name:        "ManagedLocation",
@@ -618,6 +713,13 @@ func TestNeoReadByConceptID(t *testing.T) {
 			expectedLen: 7,
 			expected:    expectedConcordanceBankOfTest,
 		},
+		{
+			name:        "FinancialInstrument",
+			fixture:     "FinancialInstrument-BankOfTestEquity-6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93.json",
+			conceptIDs:  []string{"6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+			expectedLen: 5,
+			expected:    expectedConcordanceBankOfTestEquity,
+		},
 		{
 			name:        "NAICSIndustryClassification",
 			fixture:     "NAICSIndustryClassification-38ee195d-ebdd-48a9-af4b-c8a322e7b04d.json",
@@ -687,28 +789,28 @@ func TestNeoReadByConceptID(t *testing.T) {
 			conceptIDs:  []string{"97b56e0e-3526-4434-ad29-349b06ead4a3"},
 			expectedLen: 3,
 			expected: Concordances{
-				[]Concordance{
+				Concordance: []Concordance{
 					{
-						Concept{
+						Concept: Concept{
 							ID:     "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3",
 							APIURL: "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3"},
-						Identifier{
+						Identifier: Identifier{
 							Authority:       "http://api.ft.com/system/SMARTLOGIC",
 							IdentifierValue: "97b56e0e-3526-4434-ad29-349b06ead4a3"},
 					},
 					{
-						Concept{
+						Concept: Concept{
 							ID:     "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3",
 							APIURL: "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3"},
-						Identifier{
+						Identifier: Identifier{
 							Authority:       "http://api.ft.com/system/FT-AnI",
 							IdentifierValue: "ELE"},
 					},
 					{
-						Concept{
+						Concept: Concept{
 							ID:     "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3",
 							APIURL: "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3"},
-						Identifier{
+						Identifier: Identifier{
 							Authority:       "http://api.ft.com/system/UPP",
 							IdentifierValue: "97b56e0e-3526-4434-ad29-349b06ead4a3"},
 					},
@@ -722,9 +824,9 @@ func TestNeoReadByConceptID(t *testing.T) {
 			writeConceptFixture(t, driver, "./fixtures/"+test.fixture)
 			defer cleanUp(assert.New(t), driver)
 
-			undertest, err := NewCypherDriver(driver, "http://api.ft.com")
+			undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
 			assert.NoError(t, err)
-			conc, found, err := undertest.ReadByConceptID(test.conceptIDs)
+			conc, found, err := undertest.ReadByConceptID(context.Background(), test.conceptIDs, test.authorities)
 			assert.NoError(t, err)
 			assert.True(t, found)
 			assert.Equal(t, test.expectedLen, len(conc.Concordance))
@@ -750,14 +852,14 @@ func TestNeoReadByAuthority(t *testing.T) {
 			fixture:          "Brand-Concorded-b20801ac-5a76-43cf-b816-8c3b2f7133ad.json",
 			authority:        "http://api.ft.com/system/SMARTLOGIC",
 			identifierValues: []string{"b20801ac-5a76-43cf-b816-8c3b2f7133ad"},
-			expected:         Concordances{[]Concordance{concordedBrandSmartlogic}},
+			expected:         Concordances{Concordance: []Concordance{concordedBrandSmartlogic}},
 		},
 		{
 			name:             "NewModel_Unconcorded",
 			fixture:          "Brand-Unconcorded-ad56856a-7d38-48e2-a131-7d104f17e8f6.json",
 			authority:        "http://api.ft.com/system/FT-TME",
 			identifierValues: []string{"UGFydHkgcGVvcGxl-QnJhbmRz"},
-			expected:         Concordances{[]Concordance{unconcordedBrandTME}},
+			expected:         Concordances{Concordance: []Concordance{unconcordedBrandTME}},
 		},
 		{
 			name:             "ManagedLocation",
@@ -794,6 +896,27 @@ func TestNeoReadByAuthority(t *testing.T) {
 			identifierValues: []string{"VNF516RB4DFV5NQ22UF0"},
 			expected:         expectedConcordanceBankOfTestByLEIAuthority,
 		},
+		{
+			name:             "ToConcordancesByISINAuthority",
+			fixture:          "FinancialInstrument-BankOfTestEquity-6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93.json",
+			authority:        "http://api.ft.com/system/ISIN",
+			identifierValues: []string{"GB00BTST1234"},
+			expected:         expectedConcordanceBankOfTestEquityByISINAuthority,
+		},
+		{
+			name:             "ToConcordancesByFIGIAuthority",
+			fixture:          "FinancialInstrument-BankOfTestEquity-6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93.json",
+			authority:        "http://api.ft.com/system/FIGI",
+			identifierValues: []string{"BBG000BTST12"},
+			expected:         expectedConcordanceBankOfTestEquityByFIGIAuthority,
+		},
+		{
+			name:             "ToConcordancesByTickerAuthority",
+			fixture:          "FinancialInstrument-BankOfTestEquity-6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93.json",
+			authority:        "http://api.ft.com/system/TICKER",
+			identifierValues: []string{"BOT"},
+			expected:         expectedConcordanceBankOfTestEquityByTickerAuthority,
+		},
 		{
 			name:             "OnlyOneConcordancePerIdentifierValue",
 			fixture:          "Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json",
@@ -870,12 +993,12 @@ func TestNeoReadByAuthority(t *testing.T) {
 			authority:        "http://api.ft.com/system/FT-AnI",
 			identifierValues: []string{"ELE"},
 			expected: Concordances{
-				[]Concordance{
+				Concordance: []Concordance{
 					{
-						Concept{
+						Concept: Concept{
 							ID:     "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3",
 							APIURL: "http://api.ft.com/things/97b56e0e-3526-4434-ad29-349b06ead4a3"},
-						Identifier{
+						Identifier: Identifier{
 							Authority:       "http://api.ft.com/system/FT-AnI",
 							IdentifierValue: "ELE"},
 					},
@@ -895,9 +1018,9 @@ func TestNeoReadByAuthority(t *testing.T) {
 			writeConceptFixture(t, driver, "./fixtures/"+test.fixture)
 			defer cleanUp(assert.New(t), driver)
 
-			undertest, err := NewCypherDriver(driver, "http://api.ft.com")
+			undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
 			assert.NoError(t, err)
-			conc, found, err := undertest.ReadByAuthority(test.authority, test.identifierValues)
+			conc, found, err := undertest.ReadByAuthority(context.Background(), test.authority, test.identifierValues)
 			assert.NoError(t, err)
 
 			if len(test.expected.Concordance) > 0 {
@@ -913,7 +1036,269 @@ func TestNeoReadByAuthority(t *testing.T) {
 	}
 }
 
+func TestNeoReadConceptMetadata(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	byConceptID, _, err := undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.NoError(t, err)
+	byAuthority, _, err := undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/LEI", []string{"VNF516RB4DFV5NQ22UF0"})
+	assert.NoError(t, err)
+
+	for _, c := range append(byConceptID.Concordance, byAuthority.Concordance...) {
+		assert.Equal(t, "Bank of Test", c.Concept.PrefLabel)
+		assert.Equal(t, "Organisation", c.Concept.Type)
+	}
+}
+
+func TestNeoFinancialInstrumentIdentifiersSurviveConceptWrite(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/FinancialInstrument-BankOfTestEquity-6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93.json")
+	defer cleanUp(assert.New(t), driver)
+
+	var written []struct {
+		ISIN     string `json:"isin"`
+		Ticker   string `json:"ticker"`
+		FIGICode string `json:"figiCode"`
+	}
+	err := driver.Read(&cmneo4j.Query{
+		Cypher: `MATCH (canonical:Concept {prefUUID: $uuid})
+			RETURN canonical.isin AS isin, canonical.ticker AS ticker, canonical.figiCode AS figiCode`,
+		Params: map[string]interface{}{"uuid": "6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93"},
+		Result: &written,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, written, 1) {
+		assert.Equal(t, "GB00BTST1234", written[0].ISIN)
+		assert.Equal(t, "BOT", written[0].Ticker)
+		assert.Equal(t, "BBG000BTST12", written[0].FIGICode)
+	}
+}
+
+func TestNeoTranslate(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		from             string
+		to               string
+		identifierValues []string
+		expected         []Identifier
+	}{
+		{
+			name:             "LeafNodeToProperty",
+			from:             "http://api.ft.com/system/FACTSET",
+			to:               "http://api.ft.com/system/LEI",
+			identifierValues: []string{"7IV872-E"},
+			expected:         []Identifier{{Authority: "http://api.ft.com/system/LEI", IdentifierValue: "VNF516RB4DFV5NQ22UF0"}},
+		},
+		{
+			name:             "PropertyToLeafNode",
+			from:             "http://api.ft.com/system/LEI",
+			to:               "http://api.ft.com/system/FT-TME",
+			identifierValues: []string{"VNF516RB4DFV5NQ22UF0"},
+			expected:         []Identifier{{Authority: "http://api.ft.com/system/FT-TME", IdentifierValue: "QmFuayBvZiBUZXN0-T04="}},
+		},
+		{
+			name:             "LeafNodeToUPP",
+			from:             "http://api.ft.com/system/FT-TME",
+			to:               "http://api.ft.com/system/UPP",
+			identifierValues: []string{"QmFuayBvZiBUZXN0-T04="},
+			expected: []Identifier{
+				{Authority: "http://api.ft.com/system/UPP", IdentifierValue: "2cdeb859-70df-3a0e-b125-f958366bea44"},
+				{Authority: "http://api.ft.com/system/UPP", IdentifierValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
+				{Authority: "http://api.ft.com/system/UPP", IdentifierValue: "d56e7388-25cb-343e-aea9-8b512e28476e"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			translations, found, err := undertest.Translate(context.Background(), test.from, test.to, test.identifierValues)
+			assert.NoError(t, err)
+			assert.True(t, found)
+
+			var actual []Identifier
+			for _, translation := range translations {
+				assert.Equal(t, Identifier{Authority: test.from, IdentifierValue: test.identifierValues[0]}, translation.From)
+				assert.Equal(t, "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", translation.Concept.ID)
+				actual = append(actual, translation.To)
+			}
+			assert.ElementsMatch(t, test.expected, actual)
+		})
+	}
+}
+
+func TestNeoReadInputType(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		read      func() (Concordances, bool, error)
+		input     string
+		inputType string
+	}{
+		{
+			name: "CanonicalConceptID",
+			read: func() (Concordances, bool, error) {
+				return undertest.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+			},
+			input:     "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+			inputType: InputTypeCanonical,
+		},
+		{
+			name: "LeafConceptID",
+			read: func() (Concordances, bool, error) {
+				return undertest.ReadByConceptID(context.Background(), []string{"d56e7388-25cb-343e-aea9-8b512e28476e"}, nil)
+			},
+			input:     "d56e7388-25cb-343e-aea9-8b512e28476e",
+			inputType: InputTypeLeaf,
+		},
+		{
+			name: "LeafAuthority",
+			read: func() (Concordances, bool, error) {
+				return undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+			},
+			input:     "7IV872-E",
+			inputType: InputTypeLeaf,
+		},
+		{
+			name: "PropertyAuthority",
+			read: func() (Concordances, bool, error) {
+				return undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/LEI", []string{"VNF516RB4DFV5NQ22UF0"})
+			},
+			input:     "VNF516RB4DFV5NQ22UF0",
+			inputType: InputTypeCanonical,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conc, found, err := test.read()
+			assert.NoError(t, err)
+			assert.True(t, found)
+			for _, c := range conc.Concordance {
+				assert.Equal(t, test.input, c.Input)
+				assert.Equal(t, test.inputType, c.InputType)
+				assert.False(t, c.Concept.IsDeprecated)
+			}
+		})
+	}
+}
+
+func TestNeoReadByConceptIDEchoesEveryInput(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	inputs := []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "d56e7388-25cb-343e-aea9-8b512e28476e"}
+	conc, found, err := undertest.ReadByConceptID(context.Background(), inputs, []string{"http://api.ft.com/system/FACTSET"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	matched := matchInputs(inputs, conc.Concordance)
+	for _, input := range inputs {
+		assert.Len(t, matched[input], 1)
+		assert.Equal(t, "7IV872-E", matched[input][0].Identifier.IdentifierValue)
+	}
+}
+
+func TestNeoReadPageCoversEveryConcordanceOnce(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	lookup := Lookup{ConceptIDs: []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}}
+	all, found, err := undertest.ReadByConceptID(context.Background(), lookup.ConceptIDs, nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	var paged []Concordance
+	var after *PagePosition
+	for {
+		page, _, err := undertest.ReadPage(context.Background(), lookup, Page{After: after, Limit: 2})
+		assert.NoError(t, err)
+		paged = append(paged, page.Concordance...)
+		if len(page.Concordance) < 2 {
+			break
+		}
+		last := positionOf(page.Concordance[1])
+		after = &last
+	}
+
+	sortConcordances(all.Concordance)
+	sortConcordances(paged)
+	assert.Equal(t, all.Concordance, paged)
+}
+
+func TestNeoExportPage(t *testing.T) {
+	driver := getNeoDriver(assert.New(t))
+	writeConceptFixture(t, driver, "./fixtures/Organisation-BankOfTest-cd7e4345-f11f-41f3-a0f0-2cf5c43e0115.json")
+	defer cleanUp(assert.New(t), driver)
+
+	undertest, err := NewCypherDriver(driver, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		authority string
+		value     string
+	}{
+		{authority: "http://api.ft.com/system/FACTSET", value: "7IV872-E"},
+		{authority: "http://api.ft.com/system/LEI", value: "VNF516RB4DFV5NQ22UF0"},
+	}
+	for _, test := range tests {
+		t.Run(test.authority, func(t *testing.T) {
+			var exported []Concordance
+			after := ""
+			for {
+				page, next, err := undertest.ExportPage(context.Background(), test.authority, after, 100)
+				assert.NoError(t, err)
+				exported = append(exported, page.Concordance...)
+				if next == "" {
+					break
+				}
+				after = next
+			}
+
+			found := false
+			for _, c := range exported {
+				if c.Identifier.IdentifierValue == test.value {
+					found = true
+					assert.Equal(t, "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", c.Concept.ID)
+				}
+			}
+			assert.True(t, found)
+		})
+	}
+}
+
 func readConceptAndCompare(t *testing.T, expected Concordances, actual Concordances, testName string) {
+	// prefLabel and type are covered by TestNeoReadConceptMetadata, input and inputType by TestNeoReadInputType
+	actual.Concordance = includedFields{input: true}.apply(actual.Concordance)
+	for i := range actual.Concordance {
+		actual.Concordance[i].Input = ""
+		actual.Concordance[i].InputType = ""
+	}
 
 	sortConcordances(expected.Concordance)
 	sortConcordances(actual.Concordance)
@@ -974,6 +1359,7 @@ func cleanUp(assert *assert.Assertions, driver *cmneo4j.Driver) {
 		"b20801ac-5a76-43cf-b816-8c3b2f7133ad",
 		"ad56856a-7d38-48e2-a131-7d104f17e8f6",
 		"38ee195d-ebdd-48a9-af4b-c8a322e7b04d",
+		"6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93",
 	}
 	for _, uuid := range uuids {
 		query := &cmneo4j.Query{
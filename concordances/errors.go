@@ -0,0 +1,61 @@
+package concordances
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine readable reason for a request failing, which clients can rely on instead of the message
+type ErrorCode string
+
+const (
+	CodeConceptAndIdentifierValuePresent ErrorCode = "CONCEPT_AND_IDENTIFIER_VALUE_PRESENT"
+	CodeConceptOrAuthorityMissing        ErrorCode = "CONCEPT_OR_AUTHORITY_MISSING"
+	CodeIdentifierValueWithoutAuthority  ErrorCode = "IDENTIFIER_VALUE_WITHOUT_AUTHORITY"
+	CodeInvalidParameter                 ErrorCode = "INVALID_PARAMETER"
+	CodeInvalidInput                     ErrorCode = "INVALID_INPUT"
+	CodeInvalidRequestBody               ErrorCode = "INVALID_REQUEST_BODY"
+	CodeEmptyRequest                     ErrorCode = "EMPTY_REQUEST"
+	CodeAuthorityMissing                 ErrorCode = "AUTHORITY_MISSING"
+	CodeBatchSizeExceeded                ErrorCode = "BATCH_SIZE_EXCEEDED"
+	CodeNotFound                         ErrorCode = "NOT_FOUND"
+	CodeNotAcceptable                    ErrorCode = "NOT_ACCEPTABLE"
+	CodeDatastoreError                   ErrorCode = "DATASTORE_ERROR"
+	CodeDatastoreTimeout                 ErrorCode = "DATASTORE_TIMEOUT"
+)
+
+// APIError is the body of every 4xx and 5xx response, Status is the HTTP status code it is sent with.
+// InvalidInputs lists every rejected identifier when the request failed validation.
+type APIError struct {
+	Status        int            `json:"-"`
+	Code          ErrorCode      `json:"code"`
+	Message       string         `json:"message"`
+	Parameter     string         `json:"parameter,omitempty"`
+	TransactionID string         `json:"transactionId,omitempty"`
+	InvalidInputs []InvalidInput `json:"invalidInputs,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func newBadRequestError(code ErrorCode, parameter string, message string) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: code, Message: message, Parameter: parameter}
+}
+
+func newInvalidInputError(invalid []InvalidInput) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: CodeInvalidInput, Message: invalidInputs, InvalidInputs: invalid}
+}
+
+func writeErrorResponse(w http.ResponseWriter, tid string, apiErr *APIError) error {
+	body := *apiErr
+	body.TransactionID = tid
+
+	w.WriteHeader(apiErr.Status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		return fmt.Errorf("error while writing response message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package concordances
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// sortCanonically orders the concordances by concept, authority, identifierValue and input, and the identifiers
+// not found alphabetically, so that the same concordances are always encoded into the same body
+func sortCanonically(concordances Concordances) {
+	sort.SliceStable(concordances.Concordance, func(i, j int) bool {
+		a, b := concordances.Concordance[i], concordances.Concordance[j]
+		if a.Concept.ID != b.Concept.ID {
+			return a.Concept.ID < b.Concept.ID
+		}
+		if a.Identifier.Authority != b.Identifier.Authority {
+			return a.Identifier.Authority < b.Identifier.Authority
+		}
+		if a.Identifier.IdentifierValue != b.Identifier.IdentifierValue {
+			return a.Identifier.IdentifierValue < b.Identifier.IdentifierValue
+		}
+		if a.Input != b.Input {
+			return a.Input < b.Input
+		}
+		return a.InputType < b.InputType
+	})
+	sort.Strings(concordances.NotFound)
+}
+
+// strongETag is the ETag of the response body, it changes whenever a single byte of the body does
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches tells whether one of the If-None-Match entity tags, or a wildcard, matches the etag.
+// As If-None-Match uses weak comparison the W/ prefix of the entity tags is ignored.
+func etagMatches(header http.Header, etag string) bool {
+	for _, ifNoneMatch := range header.Values("If-None-Match") {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
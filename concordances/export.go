@@ -0,0 +1,178 @@
+package concordances
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// exportMediaTypes are the media types an export is streamed in, NDJSON by default
+var exportMediaTypes = []string{ndjsonMediaType, csvMediaType}
+
+const (
+	// exportPageSize is the number of identifierValues exported by a single query
+	exportPageSize = 1000
+	// exportCacheControl keeps exports out of caches, an export cut short must not be served again as a complete one
+	exportCacheControl = "no-store"
+
+	exportAuthorityIsMandatory = "authority is mandatory"
+	unknownExportAuthority     = "authority must be the URI of a known authority"
+	invalidAfterConceptID      = "afterConceptId must be the conceptId of the last concordance received, along with its identifierValue as after"
+)
+
+// exportLimiter spaces out the export queries of all requests evenly so that exports cannot starve live lookups
+type exportLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// newExportLimiter allows pagesPerSecond export queries per second, it is nil and allows any rate when not positive
+func newExportLimiter(pagesPerSecond int) *exportLimiter {
+	if pagesPerSecond <= 0 {
+		return nil
+	}
+	return &exportLimiter{interval: time.Second / time.Duration(pagesPerSecond)}
+}
+
+// wait blocks until the next export query may run, or until ctx is done. The slot of a query cancelled while waiting
+// is given back unless a later one was reserved meanwhile.
+func (l *exportLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	slot := l.next
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.next.Equal(slot.Add(l.interval)) {
+			l.next = slot
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Export streams every concordance of an authority in the order of identifierValue, as NDJSON or as CSV when accepted.
+// An interrupted export is resumed by passing the last identifierValue received as after, along with the conceptId it
+// was received for as afterConceptId to resume exactly after it rather than after every concept of the identifierValue.
+// The optional concept fields are only exported when asked for with include, as for lookups.
+func (hh *HTTPHandler) Export(w http.ResponseWriter, r *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(r)
+	logEntry := hh.log.WithTransactionID(tid)
+	logEntry.Debugf("Export request: %s", r.URL.RawQuery)
+	m := r.URL.Query()
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Cache-Control", exportCacheControl)
+	authority := m.Get("authority")
+	if authority == "" {
+		writeError(w, logEntry, tid, newBadRequestError(CodeAuthorityMissing, "authority", exportAuthorityIsMandatory))
+		return
+	}
+	if _, found := AuthorityFromURI(authority); !found {
+		writeError(w, logEntry, tid, newBadRequestError(CodeInvalidParameter, "authority", unknownExportAuthority))
+		return
+	}
+	after, afterConceptID := m.Get("after"), strings.TrimPrefix(m.Get("afterConceptId"), thingURIPrefix)
+	if m.Has("afterConceptId") && (after == "" || !uuidPattern.MatchString(afterConceptID)) {
+		writeError(w, logEntry, tid, newBadRequestError(CodeInvalidParameter, "afterConceptId", invalidAfterConceptID))
+		return
+	}
+	include, apiErr := parseInclude(m["include"])
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+	mediaType, acceptable := negotiateMediaType(r.Header, exportMediaTypes...)
+	if !acceptable {
+		writeError(w, logEntry, tid, newNotAcceptableError(exportMediaTypes))
+		return
+	}
+
+	var writer concordanceWriter
+	flusher, canFlush := w.(http.Flusher)
+	for started := false; ; started = true {
+		err := hh.exportLimiter.wait(r.Context())
+		var page Concordances
+		var next string
+		if err == nil && afterConceptID != "" {
+			page, err = hh.restOfExportedValue(r.Context(), authority, after, afterConceptID)
+			next, afterConceptID = after, ""
+		} else if err == nil {
+			page, next, err = hh.concordanceDriver.ExportPage(r.Context(), authority, after, exportPageSize)
+		}
+		if err != nil {
+			if !started {
+				writeLookupError(w, logEntry, tid, err)
+				return
+			}
+			logEntry.WithError(err).Errorf("Export of %s cut short after %q", authority, after)
+			return
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", contentType(mediaType))
+			w.Header().Add("Vary", "Accept")
+			w.WriteHeader(http.StatusOK)
+			writer = newConcordanceWriter(w, mediaType)
+		}
+
+		for _, concordance := range include.apply(page.Concordance) {
+			if err = writer.write(concordance); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = writer.flush()
+		}
+		if err != nil {
+			logEntry.WithError(err).Errorf("Export of %s cut short after %q", authority, after)
+			return
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+
+		if next == "" {
+			return
+		}
+		after = next
+	}
+}
+
+// restOfExportedValue reads the concordances of the identifierValue of the authority whose canonical concept comes
+// after afterConceptID, in the order an export lists them in
+func (hh *HTTPHandler) restOfExportedValue(ctx context.Context, authority string, value string, afterConceptID string) (Concordances, error) {
+	read, _, err := hh.concordanceDriver.ReadByAuthority(ctx, authority, []string{value})
+	if err != nil {
+		return Concordances{}, err
+	}
+	rest := Concordances{Concordance: []Concordance{}}
+	for _, c := range read.Concordance {
+		if c.Identifier == (Identifier{Authority: authority, IdentifierValue: value}) && strings.TrimPrefix(c.Concept.ID, thingURL) > afterConceptID {
+			rest.Concordance = append(rest.Concordance, c)
+		}
+	}
+	sort.SliceStable(rest.Concordance, func(i, j int) bool {
+		return rest.Concordance[i].Concept.ID < rest.Concordance[j].Concept.ID
+	})
+	return rest, nil
+}
@@ -0,0 +1,45 @@
+package concordances
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportLimiterSpacesQueriesOut(t *testing.T) {
+	limiter := newExportLimiter(50)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond, "the second and third queries should wait 20ms each")
+}
+
+func TestExportLimiterStopsWaitingWhenTheContextIsDone(t *testing.T) {
+	limiter := newExportLimiter(1)
+	assert.NoError(t, limiter.wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.wait(ctx), context.DeadlineExceeded)
+}
+
+func TestExportLimiterGivesBackTheSlotOfACancelledQuery(t *testing.T) {
+	limiter := newExportLimiter(1)
+	assert.NoError(t, limiter.wait(context.Background()))
+	reserved := limiter.next
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, reserved, limiter.next, "the next query should take the slot of the cancelled one")
+}
+
+func TestExportIsNotLimitedWithoutARate(t *testing.T) {
+	limiter := newExportLimiter(0)
+	assert.Nil(t, limiter)
+	assert.NoError(t, limiter.wait(context.Background()))
+}
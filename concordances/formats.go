@@ -0,0 +1,140 @@
+package concordances
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonMediaType = "application/json"
+	// ndjsonMediaType streams the concordances, one JSON object per line
+	ndjsonMediaType = "application/x-ndjson"
+	// csvMediaType streams the concordances flattened into csvColumns
+	csvMediaType = "text/csv"
+
+	notAcceptable = "Accept must allow one of "
+)
+
+// csvColumns is the header of the flattened CSV layout of concordances
+var csvColumns = []string{"conceptId", "apiUrl", "authority", "identifierValue"}
+
+func csvRecord(c Concordance) []string {
+	return []string{c.Concept.ID, c.Concept.APIURL, c.Identifier.Authority, c.Identifier.IdentifierValue}
+}
+
+// negotiateMediaType picks the supported media type the Accept header prefers, the first supported one when
+// the header is absent. The quality of a media type is the one of the most specific media range matching it,
+// among media types of the same quality the first supported one wins.
+// acceptable is false when the header does not allow any of them.
+func negotiateMediaType(header http.Header, supported ...string) (mediaType string, acceptable bool) {
+	accepts := header.Values("Accept")
+	if len(accepts) == 0 {
+		return supported[0], true
+	}
+
+	var bestQuality float64
+	for _, s := range supported {
+		specificity, quality := -1, 0.0
+		for _, accept := range accepts {
+			for _, mediaRange := range strings.Split(accept, ",") {
+				candidate, q := parseMediaRange(mediaRange)
+				if match := mediaRangeSpecificity(candidate, s); match > specificity {
+					specificity, quality = match, q
+				}
+			}
+		}
+		if quality > bestQuality {
+			mediaType, bestQuality = s, quality
+		}
+	}
+	return mediaType, bestQuality > 0
+}
+
+// parseMediaRange splits a media range of an Accept header into its media type and quality, which defaults to 1
+func parseMediaRange(mediaRange string) (string, float64) {
+	parts := strings.Split(mediaRange, ";")
+	quality := 1.0
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.EqualFold(name, "q") {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", 0
+			}
+			quality = q
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(parts[0])), quality
+}
+
+// mediaRangeSpecificity is 2 when the media range is the media type, 1 when it is its type/* and 0 when it is */*,
+// it is -1 when the media range does not match the media type
+func mediaRangeSpecificity(mediaRange string, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	case mediaRange == "*/*":
+		return 0
+	}
+	return -1
+}
+
+func newNotAcceptableError(supported []string) *APIError {
+	return &APIError{Status: http.StatusNotAcceptable, Code: CodeNotAcceptable, Message: notAcceptable + strings.Join(supported, ", ")}
+}
+
+// contentType is the Content-Type header of a response of the media type
+func contentType(mediaType string) string {
+	if mediaType == ndjsonMediaType {
+		return mediaType
+	}
+	return mediaType + "; charset=UTF-8"
+}
+
+// concordanceWriter writes concordances one at a time in one of the streamed media types
+type concordanceWriter interface {
+	write(c Concordance) error
+	// flush sends what was written so far
+	flush() error
+}
+
+// newConcordanceWriter creates the writer of the streamed media type, the CSV one starts with the header
+func newConcordanceWriter(w io.Writer, mediaType string) concordanceWriter {
+	if mediaType == csvMediaType {
+		writer := csvConcordanceWriter{csv.NewWriter(w)}
+		writer.csv.Write(csvColumns)
+		return writer
+	}
+	return ndjsonConcordanceWriter{json.NewEncoder(w)}
+}
+
+type ndjsonConcordanceWriter struct {
+	encoder *json.Encoder
+}
+
+func (n ndjsonConcordanceWriter) write(c Concordance) error {
+	return n.encoder.Encode(c)
+}
+
+func (n ndjsonConcordanceWriter) flush() error {
+	return nil
+}
+
+type csvConcordanceWriter struct {
+	csv *csv.Writer
+}
+
+func (c csvConcordanceWriter) write(concordance Concordance) error {
+	return c.csv.Write(csvRecord(concordance))
+}
+
+func (c csvConcordanceWriter) flush() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
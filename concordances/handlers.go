@@ -1,10 +1,12 @@
 package concordances
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"errors"
@@ -20,6 +22,8 @@ type HTTPHandler struct {
 	log                *logger.UPPLogger
 	concordanceDriver  Driver
 	cacheControlHeader string
+	maxBatchSize       int
+	exportLimiter      *exportLimiter
 }
 
 const (
@@ -27,54 +31,87 @@ const (
 
 	thingURIPrefix = "http://api.ft.com/things/"
 
-	multipleAuthoritiesNotPermitted          = "multiple authorities are not permitted"
-	conceptAndAuthorityCannotBeBothPresent   = "if conceptId is present then authority is not a valid parameter"
+	identifierValueWithoutAuthority          = "when several authorities are present every identifierValue must follow the authority it belongs to"
+	conceptAndIdentifierValueBothPresent     = "if conceptId is present then identifierValue is not a valid parameter"
 	authorityIsMandatoryIfConceptIDIsMissing = "if conceptId is absent then authority is mandatory"
 	neitherConceptIDNorAuthorityPresent      = "neither conceptId nor authority were present"
 	errAccessingConcordanceDatastore         = "error accessing Concordance datastore"
+	timeoutAccessingConcordanceDatastore     = "timed out accessing Concordance datastore"
+	invalidBulkRequestBody                   = "request body must be a JSON object with conceptIds and/or authorities"
+	emptyBulkRequest                         = "at least one conceptId or authority with identifierValues is required"
+	bulkAuthorityIsMandatory                 = "every identifierValues group must have an authority"
+	batchSizeExceeded                        = "number of requested identifiers exceeds the maximum batch size"
+	invalidStrictParameter                   = "strict must be either true or false"
+	noConcordancesFound                      = "no concordances found for any of the requested identifiers"
+	invalidIncludeParameter                  = "include must be a comma separated list of prefLabel, type and input"
+	invalidFormatParameter                   = "format must be either flat or grouped"
+	translationAuthoritiesAreMandatory       = "both the from and to authorities are mandatory"
+
+	includePrefLabel = "prefLabel"
+	includeType      = "type"
+	includeInput     = "input"
+
+	formatFlat    = "flat"
+	formatGrouped = "grouped"
+	// groupedMediaType can be accepted instead of passing format=grouped
+	groupedMediaType = "application/vnd.ft-upp-concordances-grouped+json"
 )
 
-func NewHTTPHandler(log *logger.UPPLogger, driver Driver, cacheControlHeader string) *HTTPHandler {
+// concordancesMediaTypes are the media types concordances are returned in, JSON by default
+var concordancesMediaTypes = []string{jsonMediaType, groupedMediaType, ndjsonMediaType, csvMediaType}
+
+// NewHTTPHandler creates the handler, maxBatchSize caps the number of identifiers a single request may contain
+// and exportRateLimit the number of export queries per second across all exports, unless it is not positive.
+func NewHTTPHandler(log *logger.UPPLogger, driver Driver, cacheControlHeader string, maxBatchSize int, exportRateLimit int) *HTTPHandler {
 	return &HTTPHandler{
 		log:                log,
 		concordanceDriver:  driver,
 		cacheControlHeader: cacheControlHeader,
+		maxBatchSize:       maxBatchSize,
+		exportLimiter:      newExportLimiter(exportRateLimit),
 	}
 }
 
-// HealthCheck provides an FT standard timed healthcheck for the /__health endpoint
-func (hh *HTTPHandler) HealthCheck(serviceName string) fthealth.TimedHealthCheck {
+// HealthCheck provides an FT standard timed healthcheck for the /__health endpoint made of the checks of the backend
+func (hh *HTTPHandler) HealthCheck(serviceName string, backendChecks ...fthealth.Check) fthealth.TimedHealthCheck {
 	return fthealth.TimedHealthCheck{
 		HealthCheck: fthealth.HealthCheck{
 			SystemCode:  serviceName,
 			Name:        serviceName,
 			Description: "Concords concept identifiers",
-			Checks: []fthealth.Check{
-				{
-					BusinessImpact:   "Unable to respond to Public Concordances API requests",
-					Name:             "Check connectivity to Neo4j",
-					PanicGuide:       "https://runbooks.ftops.tech/public-concordances-api",
-					Severity:         1,
-					TechnicalSummary: "Cannot connect to Neo4j a instance with at least one concordance loaded in it",
-					Checker:          hh.databaseConnectivityChecker,
-				},
-			},
+			Checks:      backendChecks,
 		},
 		Timeout: healthCheckTimeout,
 	}
 }
 
-func (hh *HTTPHandler) databaseConnectivityChecker() (string, error) {
-	connCheck := hh.concordanceDriver.CheckConnectivity()
-	if connCheck == nil {
-		return "Connectivity to neo4j is ok", connCheck
+// Neo4jConnectivityCheck is the healthcheck of the neo4j backend, failing when the driver cannot connect to Neo4j
+func Neo4jConnectivityCheck(driver Driver) fthealth.Check {
+	return fthealth.Check{
+		BusinessImpact:   "Unable to respond to Public Concordances API requests",
+		Name:             "Check connectivity to Neo4j",
+		PanicGuide:       "https://runbooks.ftops.tech/public-concordances-api",
+		Severity:         1,
+		TechnicalSummary: "Cannot connect to Neo4j a instance with at least one concordance loaded in it",
+		Checker: func() (string, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+
+			if err := driver.CheckConnectivity(ctx); err != nil {
+				return "Error connecting to neo4j", err
+			}
+			return "Connectivity to neo4j is ok", nil
+		},
 	}
-	return "Error connecting to neo4j", connCheck
 }
 
-// GTG lightly checks the application and conforms to the FT standard GTG format
+// GTG lightly checks the application and conforms to the FT standard GTG format.
+// It checks the connectivity of the backend, which only the neo4j backend can lose.
 func (hh *HTTPHandler) GTG() gtg.Status {
-	if _, err := hh.databaseConnectivityChecker(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	if err := hh.concordanceDriver.CheckConnectivity(ctx); err != nil {
 		return gtg.Status{GoodToGo: false, Message: err.Error()}
 	}
 	return gtg.Status{GoodToGo: true}
@@ -89,72 +126,486 @@ func (hh *HTTPHandler) GetConcordances(w http.ResponseWriter, r *http.Request) {
 
 	_, conceptIDExist := m["conceptId"]
 	_, authorityExist := m["authority"]
+	_, identifierValueExist := m["identifierValue"]
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	if conceptIDExist && authorityExist {
-		err := writeErrorResponse(w, http.StatusBadRequest, conceptAndAuthorityCannotBeBothPresent)
-		if err != nil {
-			logEntry.WithError(err).Errorf("cannot write response message: %s", conceptAndAuthorityCannotBeBothPresent)
-		}
+	if conceptIDExist && identifierValueExist {
+		writeError(w, logEntry, tid, newBadRequestError(CodeConceptAndIdentifierValuePresent, "identifierValue", conceptAndIdentifierValueBothPresent))
 		return
 	}
 
 	if !conceptIDExist && !authorityExist {
-		err := writeErrorResponse(w, http.StatusBadRequest, authorityIsMandatoryIfConceptIDIsMissing)
+		writeError(w, logEntry, tid, newBadRequestError(CodeConceptOrAuthorityMissing, "authority", authorityIsMandatoryIfConceptIDIsMissing))
+		return
+	}
+
+	strict := false
+	if m.Has("strict") {
+		var err error
+		strict, err = strconv.ParseBool(m.Get("strict"))
 		if err != nil {
-			logEntry.WithError(err).Errorf("cannot write response message: %s", authorityIsMandatoryIfConceptIDIsMissing)
+			writeError(w, logEntry, tid, newBadRequestError(CodeInvalidParameter, "strict", invalidStrictParameter))
+			return
 		}
+	}
+
+	include, apiErr := parseInclude(m["include"])
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	// every response depends on the Accept header, including the errors of the requests accepting no supported type
+	w.Header().Set("Vary", "Accept")
+	mediaType, acceptable := negotiateMediaType(r.Header, concordancesMediaTypes...)
+	if !acceptable {
+		writeError(w, logEntry, tid, newNotAcceptableError(concordancesMediaTypes))
+		return
+	}
+	grouped, apiErr := groupedFormatRequested(m, mediaType)
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	page, paged, apiErr := parsePage(m)
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
 		return
 	}
 
-	if len(m["authority"]) > 1 {
-		err := writeErrorResponse(w, http.StatusBadRequest, multipleAuthoritiesNotPermitted)
+	// alongside conceptIds the authorities only restrict which identifiers are returned
+	var authorityFilter []string
+	if conceptIDExist {
+		authorityFilter = m["authority"]
+	}
+
+	var groups []AuthorityIdentifiers
+	if authorityExist && !conceptIDExist {
+		var err error
+		groups, err = authorityGroups(r.URL.RawQuery)
 		if err != nil {
-			logEntry.WithError(err).Errorf("cannot write response message: %s", multipleAuthoritiesNotPermitted)
+			writeError(w, logEntry, tid, newBadRequestError(CodeIdentifierValueWithoutAuthority, "identifierValue", identifierValueWithoutAuthority))
+			return
 		}
+	}
+
+	conceptUuids, invalid := validateConceptIDs(m["conceptId"])
+	groups, invalidValues := validateAuthorityGroups(groups)
+	if invalid = append(invalid, invalidValues...); len(invalid) > 0 {
+		writeError(w, logEntry, tid, newInvalidInputError(invalid))
+		return
+	}
+	if apiErr := hh.checkBatchSize(countIdentifiers(conceptUuids, groups)); apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
 		return
 	}
 
-	concordance, _, err := hh.processParams(conceptIDExist, authorityExist, m)
+	lookup := Lookup{ConceptIDs: conceptUuids, Authorities: authorityFilter, Groups: groups}
+	if mediaType == ndjsonMediaType || mediaType == csvMediaType {
+		hh.streamConcordances(r.Context(), w, logEntry, tid, mediaType, lookup, page, strict, include)
+		return
+	}
+
+	var concordance Concordances
+	var found bool
+	var err error
+	if paged {
+		concordance, found, err = hh.readPage(r.Context(), lookup, page)
+	} else {
+		concordance, found, err = hh.processParams(r.Context(), conceptUuids, authorityFilter, groups)
+	}
 	if err != nil {
-		logEntry.WithError(err).Errorf("error looking up Concordances")
-		err := writeErrorResponse(w, http.StatusInternalServerError, errAccessingConcordanceDatastore)
-		if err != nil {
-			logEntry.WithError(err).Errorf("cannot write response message: %s", errAccessingConcordanceDatastore)
+		writeLookupError(w, logEntry, tid, err)
+		return
+	}
+
+	// a page past the last one is empty rather than not found
+	if strict && !found && page.After == nil {
+		writeError(w, logEntry, tid, &APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: noConcordancesFound})
+		return
+	}
+	// which identifiers are not found is only known once every page is read
+	if !paged {
+		concordance.NotFound = notFoundIdentifiers(m["conceptId"], groups, concordance.Concordance)
+	}
+	concordance.Concordance = include.apply(concordance.Concordance)
+	// a page is already in the order of pages, which its cursor is the position in
+	if !paged {
+		sortCanonically(concordance)
+	}
+
+	var body []byte
+	if grouped {
+		body, err = json.Marshal(concordance.groupByConcept())
+	} else {
+		body, err = json.Marshal(concordance)
+	}
+	if err != nil {
+		writeLookupError(w, logEntry, tid, fmt.Errorf("encoding concordances: %w", err))
+		return
+	}
+	body = append(body, '\n')
+
+	// the format parameter takes precedence over the negotiated media type, which is only echoed when it was applied
+	if grouped && mediaType == groupedMediaType {
+		w.Header().Set("Content-Type", contentType(groupedMediaType))
+	}
+	etag := strongETag(body)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", hh.cacheControlHeader)
+	if etagMatches(r.Header, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// groupedFormatRequested tells whether the concordances should be grouped by concept,
+// the format parameter takes precedence over the negotiated media type
+func groupedFormatRequested(m url.Values, mediaType string) (bool, *APIError) {
+	if m.Has("format") {
+		switch m.Get("format") {
+		case formatFlat:
+			return false, nil
+		case formatGrouped:
+			return true, nil
+		default:
+			return false, newBadRequestError(CodeInvalidParameter, "format", invalidFormatParameter)
 		}
+	}
+
+	return mediaType == groupedMediaType, nil
+}
+
+// Translate looks up the identifiers of the to authority of the concepts identified by the identifierValues
+// of the from authority
+func (hh *HTTPHandler) Translate(w http.ResponseWriter, r *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(r)
+	logEntry := hh.log.WithTransactionID(tid)
+	logEntry.Debugf("Translation request: %s", r.URL.RawQuery)
+	m := r.URL.Query()
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	for _, param := range []string{"from", "to"} {
+		if m.Get(param) == "" {
+			writeError(w, logEntry, tid, newBadRequestError(CodeAuthorityMissing, param, translationAuthoritiesAreMandatory))
+			return
+		}
+	}
+	from, to := m.Get("from"), m.Get("to")
+
+	include, apiErr := parseInclude(m["include"])
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	groups, invalid := validateAuthorityGroups([]AuthorityIdentifiers{{Authority: from, IdentifierValues: m["identifierValue"]}})
+	if len(invalid) > 0 {
+		writeError(w, logEntry, tid, newInvalidInputError(invalid))
+		return
+	}
+	if apiErr := hh.checkBatchSize(countIdentifiers(nil, groups)); apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	translations, _, err := hh.concordanceDriver.Translate(r.Context(), from, to, groups[0].IdentifierValues)
+	if err != nil {
+		writeLookupError(w, logEntry, tid, err)
 		return
 	}
 
+	translated := map[string]bool{}
+	for i, t := range translations {
+		translated[t.From.IdentifierValue] = true
+		translations[i].Concept = include.concept(t.Concept)
+	}
+	response := Translations{Translations: translations}
+	for _, value := range groups[0].IdentifierValues {
+		if !translated[value] {
+			response.NotFound = append(response.NotFound, value)
+		}
+	}
+
 	w.Header().Set("Cache-Control", hh.cacheControlHeader)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(concordance)
+	json.NewEncoder(w).Encode(response)
 }
 
-func (hh *HTTPHandler) processParams(conceptIDExist bool, authorityExist bool, m url.Values) (concordances Concordances, found bool, err error) {
-	if conceptIDExist {
-		conceptUuids := []string{}
+// PostConcordances looks up a batch of conceptIds and authority identifierValues given in the request body,
+// returning the concordances found for each requested identifier separately
+func (hh *HTTPHandler) PostConcordances(w http.ResponseWriter, r *http.Request) {
+	tid := transactionidutils.GetTransactionIDFromRequest(r)
+	logEntry := hh.log.WithTransactionID(tid)
 
-		for _, uri := range m["conceptId"] {
-			conceptUuids = append(conceptUuids, strings.TrimPrefix(uri, thingURIPrefix))
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var req BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logEntry.WithError(err).Debug("invalid bulk Concordance request body")
+		writeError(w, logEntry, tid, newBadRequestError(CodeInvalidRequestBody, "", invalidBulkRequestBody))
+		return
+	}
+	logEntry.Debugf("Bulk Concordance request for %d identifiers", req.size())
+
+	include, apiErr := parseInclude(r.URL.Query()["include"])
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	conceptUuids, groups, apiErr := hh.validateBulkRequest(req)
+	if apiErr != nil {
+		writeError(w, logEntry, tid, apiErr)
+		return
+	}
+
+	results, err := hh.processBulkRequest(r.Context(), req, conceptUuids, groups)
+	if err != nil {
+		writeLookupError(w, logEntry, tid, err)
+		return
+	}
+	// the concordances of every result are found for its single input, which is always echoed
+	include.input = true
+	for i := range results {
+		results[i].Concordances = include.apply(results[i].Concordances)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BulkResponse{Results: results})
+}
+
+// validateBulkRequest returns the distinct conceptUuids and identifierValues per authority to look up
+func (hh *HTTPHandler) validateBulkRequest(req BulkRequest) ([]string, []AuthorityIdentifiers, *APIError) {
+	if req.size() == 0 {
+		return nil, nil, newBadRequestError(CodeEmptyRequest, "conceptIds", emptyBulkRequest)
+	}
+	for _, group := range req.Authorities {
+		if group.Authority == "" {
+			return nil, nil, newBadRequestError(CodeAuthorityMissing, "authority", bulkAuthorityIsMandatory)
 		}
+	}
+	if apiErr := hh.checkBatchSize(req.size()); apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	conceptUuids, invalid := validateConceptIDs(req.ConceptIDs)
+	groups, invalidValues := validateAuthorityGroups(req.Authorities)
+	if invalid = append(invalid, invalidValues...); len(invalid) > 0 {
+		return nil, nil, newInvalidInputError(invalid)
+	}
+	return conceptUuids, groups, nil
+}
+
+func (hh *HTTPHandler) checkBatchSize(size int) *APIError {
+	if hh.maxBatchSize > 0 && size > hh.maxBatchSize {
+		return newBadRequestError(CodeBatchSizeExceeded, "", fmt.Sprintf("%s of %d", batchSizeExceeded, hh.maxBatchSize))
+	}
+	return nil
+}
+
+func (hh *HTTPHandler) processBulkRequest(ctx context.Context, req BulkRequest, conceptUuids []string, groups []AuthorityIdentifiers) ([]BulkResult, error) {
+	results := []BulkResult{}
+
+	if len(conceptUuids) > 0 {
+		concordances, _, err := hh.concordanceDriver.ReadByConceptID(ctx, conceptUuids, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := matchInputs(conceptUuids, concordances.Concordance)
+		for _, uri := range req.ConceptIDs {
+			results = append(results, BulkResult{ConceptID: uri, Concordances: matched[strings.TrimPrefix(uri, thingURIPrefix)]})
+		}
+	}
+
+	for i, group := range groups {
+		concordances, _, err := hh.concordanceDriver.ReadByAuthority(ctx, group.Authority, group.IdentifierValues)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := matchInputs(group.IdentifierValues, concordances.Concordance)
+		for _, value := range req.Authorities[i].IdentifierValues {
+			results = append(results, BulkResult{Authority: group.Authority, IdentifierValue: value, Concordances: matched[value]})
+		}
+	}
+
+	return results, nil
+}
 
-		return hh.concordanceDriver.ReadByConceptID(conceptUuids)
+// writeLookupError answers a request whose Concordance lookup failed, telling timeouts apart from other failures
+func writeLookupError(w http.ResponseWriter, logEntry *logger.LogEntry, tid string, err error) {
+	if errors.Is(err, context.Canceled) {
+		logEntry.WithError(err).Warn("request cancelled while looking up Concordances")
+		return
+	}
+	logEntry.WithError(err).Errorf("error looking up Concordances")
+
+	apiErr := &APIError{Status: http.StatusInternalServerError, Code: CodeDatastoreError, Message: errAccessingConcordanceDatastore}
+	if errors.Is(err, context.DeadlineExceeded) {
+		apiErr = &APIError{Status: http.StatusGatewayTimeout, Code: CodeDatastoreTimeout, Message: timeoutAccessingConcordanceDatastore}
 	}
+	writeError(w, logEntry, tid, apiErr)
+}
 
-	if authorityExist {
-		return hh.concordanceDriver.ReadByAuthority(m.Get("authority"), m["identifierValue"])
+func writeError(w http.ResponseWriter, logEntry *logger.LogEntry, tid string, apiErr *APIError) {
+	if err := writeErrorResponse(w, tid, apiErr); err != nil {
+		logEntry.WithError(err).Errorf("cannot write response message: %s", apiErr.Message)
+	}
+}
+
+func (hh *HTTPHandler) processParams(ctx context.Context, conceptUuids []string, authorityFilter []string, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	if len(conceptUuids) > 0 {
+		return hh.concordanceDriver.ReadByConceptID(ctx, conceptUuids, authorityFilter)
+	}
+
+	if len(groups) == 1 {
+		return hh.concordanceDriver.ReadByAuthority(ctx, groups[0].Authority, groups[0].IdentifierValues)
+	}
+	if len(groups) > 1 {
+		return hh.concordanceDriver.ReadByAuthorities(ctx, groups)
 	}
 
 	return Concordances{}, false, errors.New(neitherConceptIDNorAuthorityPresent)
 }
 
-func writeErrorResponse(w http.ResponseWriter, statusCode int, msg string) error {
-	w.WriteHeader(statusCode)
+// includedFields are the optional Concept fields a client asked for with the include parameter, and whether the
+// requested identifier every concordance was found for is echoed
+type includedFields struct {
+	prefLabel   bool
+	conceptType bool
+	input       bool
+}
 
-	payload := []byte(`{"message": "` + msg + `"}`)
-	if _, err := w.Write(payload); err != nil {
-		return fmt.Errorf("error while writing response message: %w", err)
+func parseInclude(values []string) (includedFields, *APIError) {
+	var include includedFields
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			switch strings.TrimSpace(field) {
+			case includePrefLabel:
+				include.prefLabel = true
+			case includeType:
+				include.conceptType = true
+			case includeInput:
+				include.input = true
+			default:
+				return includedFields{}, newBadRequestError(CodeInvalidParameter, "include", invalidIncludeParameter)
+			}
+		}
 	}
+	return include, nil
+}
 
-	return nil
+// apply returns a copy of the concordances without the optional fields that were not asked for. Unless the input is
+// echoed, the concordances found for several requested identifiers of the same concept are only listed once.
+func (f includedFields) apply(concordances []Concordance) []Concordance {
+	applied := make([]Concordance, 0, len(concordances))
+	seen := map[Concordance]bool{}
+	for _, c := range concordances {
+		c.Concept = f.concept(c.Concept)
+		if !f.input {
+			c.Input, c.InputType = "", ""
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+		}
+		applied = append(applied, c)
+	}
+	return applied
+}
+
+// concept returns the concept without the optional fields that were not asked for
+func (f includedFields) concept(c Concept) Concept {
+	if !f.prefLabel {
+		c.PrefLabel = ""
+	}
+	if !f.conceptType {
+		c.Type = ""
+	}
+	return c
+}
+
+// notFoundIdentifiers lists the requested conceptIds and identifierValues, as given in the request, that none of the
+// concordances was found for
+func notFoundIdentifiers(conceptIDs []string, groups []AuthorityIdentifiers, concordances []Concordance) []string {
+	var notFound []string
+	seen := map[string]bool{}
+
+	conceptUuids := []string{}
+	for _, uri := range conceptIDs {
+		conceptUuids = append(conceptUuids, strings.TrimPrefix(uri, thingURIPrefix))
+	}
+	matched := matchInputs(conceptUuids, concordances)
+	for i, uri := range conceptIDs {
+		if len(matched[conceptUuids[i]]) == 0 && !seen[uri] {
+			seen[uri] = true
+			notFound = append(notFound, uri)
+		}
+	}
+
+	for _, group := range groups {
+		var ofAuthority []Concordance
+		for _, c := range concordances {
+			if c.Identifier.Authority == group.Authority {
+				ofAuthority = append(ofAuthority, c)
+			}
+		}
+		matched := matchInputs(group.IdentifierValues, ofAuthority)
+		for _, value := range group.IdentifierValues {
+			if len(matched[value]) == 0 {
+				notFound = append(notFound, value)
+			}
+		}
+	}
+
+	return notFound
+}
+
+// authorityGroups groups the identifierValue parameters of the query under the authority parameter preceding them,
+// e.g. authority=A&identifierValue=1&authority=B&identifierValue=2. When there is a single authority it owns every
+// identifierValue regardless of the parameter order.
+func authorityGroups(rawQuery string) ([]AuthorityIdentifiers, error) {
+	var groups []AuthorityIdentifiers
+	var unowned []string
+	groupIndex := map[string]int{}
+	current := -1
+
+	for _, param := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(param, "=")
+		key, keyErr := url.QueryUnescape(key)
+		value, valueErr := url.QueryUnescape(value)
+		if keyErr != nil || valueErr != nil {
+			continue
+		}
+
+		switch key {
+		case "authority":
+			i, found := groupIndex[value]
+			if !found {
+				i = len(groups)
+				groupIndex[value] = i
+				groups = append(groups, AuthorityIdentifiers{Authority: value})
+			}
+			current = i
+		case "identifierValue":
+			if current < 0 {
+				unowned = append(unowned, value)
+				continue
+			}
+			groups[current].IdentifierValues = append(groups[current].IdentifierValues, value)
+		}
+	}
+
+	if len(unowned) > 0 {
+		if len(groups) != 1 {
+			return nil, errors.New(identifierValueWithoutAuthority)
+		}
+		groups[0].IdentifierValues = append(unowned, groups[0].IdentifierValues...)
+	}
+	return groups, nil
 }
@@ -1,10 +1,14 @@
 package concordances
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	logger "github.com/Financial-Times/go-logger/v2"
@@ -20,30 +24,88 @@ var (
 	authorityValues    []string
 	actualAuthority    string
 	cacheControlHeader string
+	readErr            error
+	mockConcordances   Concordances
+	authorityGroupsReq []AuthorityIdentifiers
+	authorityFilter    []string
+	mockTranslations   []Translation
+	translateReq       []string
+	pageLookup         Lookup
+	pagesReq           []Page
+	exportReq          []string
 )
 
+// mockExportPageSize is the number of mock concordances exported at a time
+const mockExportPageSize = 2
+
 type mockConcordanceDriver struct{}
 
-func (driver mockConcordanceDriver) ReadByConceptID(ids []string) (concordances Concordances, found bool, err error) {
+func (driver mockConcordanceDriver) ReadByConceptID(ctx context.Context, ids []string, authorities []string) (concordances Concordances, found bool, err error) {
 	conceptIds = ids
-	return Concordances{}, isFound, nil
+	authorityFilter = authorities
+	return mockConcordances, isFound, readErr
 }
-func (driver mockConcordanceDriver) ReadByAuthority(authority string, ids []string) (concordances Concordances, found bool, err error) {
+func (driver mockConcordanceDriver) ReadByAuthority(ctx context.Context, authority string, ids []string) (concordances Concordances, found bool, err error) {
 	authorityValues = ids
 	actualAuthority = authority
-	return Concordances{}, isFound, nil
+	return mockConcordances, isFound, readErr
+}
+
+func (driver mockConcordanceDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	authorityGroupsReq = groups
+	return mockConcordances, isFound, readErr
+}
+
+func (driver mockConcordanceDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error) {
+	translateReq = append([]string{from, to}, identifierValues...)
+	return mockTranslations, len(mockTranslations) > 0, readErr
+}
+
+// ReadPage returns the window of the mock concordances following the one at the position the page starts after
+func (driver mockConcordanceDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	pageLookup = lookup
+	pagesReq = append(pagesReq, page)
+	all := mockConcordances.Concordance
+	start := 0
+	for i, c := range all {
+		if page.After != nil && positionOf(c) == *page.After {
+			start = i + 1
+		}
+	}
+	end := min(start+page.Limit, len(all))
+	return Concordances{Concordance: all[start:end]}, end > start, readErr
+}
+
+// ExportPage exports the mock concordances following the one whose identifierValue is after
+func (driver mockConcordanceDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error) {
+	exportReq = append(exportReq, after)
+	all := mockConcordances.Concordance
+	start := 0
+	for i, c := range all {
+		if c.Identifier.IdentifierValue == after {
+			start = i + 1
+		}
+	}
+	end := min(start+mockExportPageSize, len(all))
+	if end < len(all) {
+		next = all[end-1].Identifier.IdentifierValue
+	}
+	return Concordances{Concordance: all[start:end]}, next, readErr
 }
 
-func (driver mockConcordanceDriver) CheckConnectivity() error {
+func (driver mockConcordanceDriver) CheckConnectivity(ctx context.Context) error {
 	return nil
 }
 
 func init() {
 	log := logger.NewUPPLogger("public-concordances-api", "panic")
 	cacheControlHeader = "max-age=30, public"
-	hh := NewHTTPHandler(log, mockConcordanceDriver{}, cacheControlHeader)
+	hh := NewHTTPHandler(log, mockConcordanceDriver{}, cacheControlHeader, 3, 0)
 	r := mux.NewRouter()
 	r.HandleFunc("/concordances", hh.GetConcordances).Methods("GET")
+	r.HandleFunc("/concordances", hh.PostConcordances).Methods("POST")
+	r.HandleFunc("/concordances/translate", hh.Translate).Methods("GET")
+	r.HandleFunc("/concordances/export", hh.Export).Methods("GET")
 	server = httptest.NewServer(r)
 	concordanceURL = fmt.Sprintf("%s/concordances", server.URL) //Grab the address for the API endpoint
 	isFound = true
@@ -73,39 +135,94 @@ func TestCanGetMultipleIdentifiersByAuthority(t *testing.T) {
 	assert.Contains(authorityValues, "some-value2")
 }
 
-func TestReturnBadRequestGivenMoreThanOneAuthority(t *testing.T) {
+func TestCanGetIdentifiersOfSeveralAuthorities(t *testing.T) {
 	assert := assert.New(t)
 	isFound = true
-	req, _ := http.NewRequest("GET", concordanceURL+"?authority=some-authority&identifierValue=some-value&authority=some-authority-yet-again", nil)
+	req, _ := http.NewRequest("GET", concordanceURL+"?authority=some-authority&identifierValue=some-value&authority=some-authority-yet-again&identifierValue=some-value2&identifierValue=some-value3", nil)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	assert.EqualValues(200, res.StatusCode)
+	assert.Equal([]AuthorityIdentifiers{
+		{Authority: "some-authority", IdentifierValues: []string{"some-value"}},
+		{Authority: "some-authority-yet-again", IdentifierValues: []string{"some-value2", "some-value3"}},
+	}, authorityGroupsReq)
+}
+
+func TestReturnBadRequestGivenIdentifierValueBeforeSeveralAuthorities(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	req, _ := http.NewRequest("GET", concordanceURL+"?identifierValue=some-value&authority=some-authority&authority=some-authority-yet-again&identifierValue=some-value2", nil)
 	res, err := http.DefaultClient.Do(req)
 	assert.NoError(err)
 	assert.EqualValues(400, res.StatusCode)
 	msg, err := ioutil.ReadAll(res.Body)
 	assert.NoError(err)
-	assert.Contains(string(msg), multipleAuthoritiesNotPermitted)
+	assert.Contains(string(msg), identifierValueWithoutAuthority)
+}
+
+func TestAuthorityGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		expected []AuthorityIdentifiers
+		err      bool
+	}{
+		{
+			name:     "SingleAuthorityOwnsAllValues",
+			rawQuery: "identifierValue=1&authority=http%3A%2F%2Fapi.ft.com%2Fsystem%2FFACTSET&identifierValue=2",
+			expected: []AuthorityIdentifiers{{Authority: "http://api.ft.com/system/FACTSET", IdentifierValues: []string{"1", "2"}}},
+		},
+		{
+			name:     "ValuesFollowTheirAuthority",
+			rawQuery: "authority=A&identifierValue=1&authority=B&identifierValue=2&identifierValue=3",
+			expected: []AuthorityIdentifiers{{Authority: "A", IdentifierValues: []string{"1"}}, {Authority: "B", IdentifierValues: []string{"2", "3"}}},
+		},
+		{
+			name:     "RepeatedAuthorityIsMerged",
+			rawQuery: "authority=A&identifierValue=1&authority=B&identifierValue=2&authority=A&identifierValue=3",
+			expected: []AuthorityIdentifiers{{Authority: "A", IdentifierValues: []string{"1", "3"}}, {Authority: "B", IdentifierValues: []string{"2"}}},
+		},
+		{
+			name:     "ValueWithoutAuthority",
+			rawQuery: "identifierValue=1&authority=A&authority=B&identifierValue=2",
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups, err := authorityGroups(test.rawQuery)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, groups)
+		})
+	}
 }
 
 func TestCanGetOneConcept(t *testing.T) {
 	assert := assert.New(t)
 	isFound = true
-	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=bob", nil)
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=2cdeb859-70df-3a0e-b125-f958366bea44", nil)
 	res, err := http.DefaultClient.Do(req)
 	assert.NoError(err)
 	assert.EqualValues(200, res.StatusCode)
 	assert.Len(conceptIds, 1)
-	assert.Contains(conceptIds, "bob")
+	assert.Contains(conceptIds, "2cdeb859-70df-3a0e-b125-f958366bea44")
 }
 
 func TestCanGetMultipleConcepts(t *testing.T) {
 	assert := assert.New(t)
 	isFound = true
-	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=bob&conceptId=carlos", nil)
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=2cdeb859-70df-3a0e-b125-f958366bea44&conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", nil)
 	res, err := http.DefaultClient.Do(req)
 	assert.NoError(err)
 	assert.EqualValues(200, res.StatusCode)
 	assert.Len(conceptIds, 2)
-	assert.Contains(conceptIds, "bob")
-	assert.Contains(conceptIds, "carlos")
+	assert.Contains(conceptIds, "2cdeb859-70df-3a0e-b125-f958366bea44")
+	assert.Contains(conceptIds, "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115")
 }
 
 func TestCanParseConceptURI(t *testing.T) {
@@ -119,10 +236,32 @@ func TestCanParseConceptURI(t *testing.T) {
 	assert.Contains(conceptIds, "8138ca3f-b80d-3ef8-ad59-6a9b6ea5f15e")
 }
 
-func TestCanNotRequestAuthorityAndConceptId(t *testing.T) {
+func TestCanFilterConceptIdByAuthority(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=8138ca3f-b80d-3ef8-ad59-6a9b6ea5f15e&authority=http://api.ft.com/system/FACTSET&authority=http://api.ft.com/system/LEI", nil)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+	assert.Equal([]string{"8138ca3f-b80d-3ef8-ad59-6a9b6ea5f15e"}, conceptIds)
+	assert.Equal([]string{"http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI"}, authorityFilter)
+}
+
+func TestConceptIdIsNotFilteredByDefault(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	res, err := http.Get(concordanceURL + "?conceptId=8138ca3f-b80d-3ef8-ad59-6a9b6ea5f15e")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+	assert.Nil(authorityFilter)
+}
+
+func TestCanNotRequestIdentifierValueAndConceptId(t *testing.T) {
 	assert := assert.New(t)
 	isFound = true
-	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=bob&authority=high-and-mighty", nil)
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=bob&authority=high-and-mighty&identifierValue=1234", nil)
 	res, err := http.DefaultClient.Do(req)
 	assert.NoError(err)
 	assert.EqualValues(400, res.StatusCode)
@@ -147,3 +286,880 @@ func TestCorrectCacheControlHeadersAreSet(t *testing.T) {
 	assert.EqualValues(200, res.StatusCode)
 	assert.EqualValues(res.Header.Get("Cache-Control"), cacheControlHeader)
 }
+
+func TestReturnGatewayTimeoutWhenQueryDeadlineIsExceeded(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	readErr = fmt.Errorf("error accessing Concordance datastore: %w", context.DeadlineExceeded)
+	defer func() { readErr = nil }()
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=2cdeb859-70df-3a0e-b125-f958366bea44", nil)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(504, res.StatusCode)
+	msg, err := ioutil.ReadAll(res.Body)
+	assert.NoError(err)
+	assert.Contains(string(msg), timeoutAccessingConcordanceDatastore)
+}
+
+func TestReturnInternalServerErrorWhenQueryFails(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	readErr = errors.New("boom")
+	defer func() { readErr = nil }()
+	req, _ := http.NewRequest("GET", concordanceURL+"?authority=some-authority&identifierValue=some-value", nil)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(500, res.StatusCode)
+}
+
+func TestBulkLookupGroupsResultsPerInput(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	byConceptID := []Concordance{foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestFactset), foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestUPP)}
+	byAuthority := []Concordance{foundFor("7IV872-E", bankOfTestFactset)}
+	mockConcordances = Concordances{Concordance: append(byConceptID, byAuthority...)}
+	defer func() { mockConcordances = Concordances{} }()
+
+	body := `{"conceptIds": ["http://api.ft.com/things/d56e7388-25cb-343e-aea9-8b512e28476e"],
+		"authorities": [{"authority": "http://api.ft.com/system/FACTSET", "identifierValues": ["7IV872-E", "unknown"]}]}`
+	res, err := http.Post(concordanceURL, "application/json", strings.NewReader(body))
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+
+	var actual BulkResponse
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal([]BulkResult{
+		{ConceptID: "http://api.ft.com/things/d56e7388-25cb-343e-aea9-8b512e28476e", Concordances: byConceptID},
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E", Concordances: byAuthority},
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "unknown", Concordances: []Concordance{}},
+	}, actual.Results)
+	assert.Equal([]string{"d56e7388-25cb-343e-aea9-8b512e28476e"}, conceptIds)
+	assert.Equal([]string{"7IV872-E", "unknown"}, authorityValues)
+}
+
+func TestBulkLookupRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		msg  string
+	}{
+		{name: "NotJSON", body: `conceptId=bob`, msg: invalidBulkRequestBody},
+		{name: "Empty", body: `{}`, msg: emptyBulkRequest},
+		{name: "MissingAuthority", body: `{"authorities": [{"identifierValues": ["7IV872-E"]}]}`, msg: bulkAuthorityIsMandatory},
+		{name: "TooManyIdentifiers", body: `{"conceptIds": ["a", "b"], "authorities": [{"authority": "x", "identifierValues": ["c", "d"]}]}`, msg: batchSizeExceeded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := http.Post(concordanceURL, "application/json", strings.NewReader(test.body))
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 400, res.StatusCode)
+			msg, err := ioutil.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.Contains(t, string(msg), test.msg)
+		})
+	}
+}
+
+func TestErrorResponsesCarryCodeParameterAndTransactionID(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", concordanceURL+"?conceptId=bob&identifierValue=1234", nil)
+	req.Header.Set("X-Request-Id", "tid_test")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(400, res.StatusCode)
+
+	var actual APIError
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(APIError{
+		Code:          CodeConceptAndIdentifierValuePresent,
+		Message:       conceptAndIdentifierValueBothPresent,
+		Parameter:     "identifierValue",
+		TransactionID: "tid_test",
+	}, actual)
+}
+
+func TestErrorResponseMessagesAreJSONEncoded(t *testing.T) {
+	assert := assert.New(t)
+	w := httptest.NewRecorder()
+	err := writeErrorResponse(w, "tid_test", newBadRequestError(CodeInvalidRequestBody, "", `unexpected "quote"`))
+	assert.NoError(err)
+	assert.EqualValues(400, w.Code)
+
+	var actual APIError
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(`unexpected "quote"`, actual.Message)
+	assert.Equal(CodeInvalidRequestBody, actual.Code)
+}
+
+func TestResponseListsIdentifiersNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "ByConceptID",
+			query:    "?conceptId=http://api.ft.com/things/d56e7388-25cb-343e-aea9-8b512e28476e&conceptId=http://api.ft.com/things/4534282c-d3ee-3595-9957-81a9293200f3",
+			expected: []string{"http://api.ft.com/things/4534282c-d3ee-3595-9957-81a9293200f3"},
+		},
+		{
+			name:     "ByAuthority",
+			query:    "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E&identifierValue=unknown",
+			expected: []string{"unknown"},
+		},
+		{
+			name:     "BySeveralAuthorities",
+			query:    "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E&authority=http://api.ft.com/system/FT-TME&identifierValue=7IV872-E",
+			expected: []string{"7IV872-E"},
+		},
+	}
+
+	mockConcordances = Concordances{Concordance: []Concordance{
+		foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestFactset),
+		foundFor("d56e7388-25cb-343e-aea9-8b512e28476e", bankOfTestUPP),
+		foundFor("7IV872-E", bankOfTestFactset),
+	}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := http.Get(concordanceURL + test.query)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 200, res.StatusCode)
+
+			var actual Concordances
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, test.expected, actual.NotFound)
+		})
+	}
+}
+
+func TestStrictModeReturnsNotFoundWhenNothingWasFound(t *testing.T) {
+	assert := assert.New(t)
+	isFound = false
+	defer func() { isFound = true }()
+
+	res, err := http.Get(concordanceURL + "?conceptId=4534282c-d3ee-3595-9957-81a9293200f3&strict=true")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(404, res.StatusCode)
+
+	var actual APIError
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(CodeNotFound, actual.Code)
+}
+
+func TestStrictModeReturnsPartialResults(t *testing.T) {
+	assert := assert.New(t)
+	isFound = true
+	bankOfTestFactset := foundFor("7IV872-E", bankOfTestFactset)
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E&identifierValue=unknown&strict=true&include=input")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+
+	var actual Concordances
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal([]Concordance{bankOfTestFactset}, actual.Concordance)
+	assert.Equal([]string{"unknown"}, actual.NotFound)
+}
+
+func TestReturnBadRequestGivenInvalidStrictParameter(t *testing.T) {
+	assert := assert.New(t)
+	res, err := http.Get(concordanceURL + "?conceptId=bob&strict=maybe")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(400, res.StatusCode)
+}
+
+func TestReturnBadRequestListingEveryInvalidInput(t *testing.T) {
+	assert := assert.New(t)
+	conceptIds = nil
+	res, err := http.Get(concordanceURL + "?conceptId=bob&conceptId=2cdeb859-70df-3a0e-b125-f958366bea44&conceptId=")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(400, res.StatusCode)
+
+	var actual APIError
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(CodeInvalidInput, actual.Code)
+	assert.Equal([]InvalidInput{
+		{Parameter: "conceptId", Value: "bob", Reason: invalidConceptID},
+		{Parameter: "conceptId", Value: "", Reason: invalidConceptID},
+	}, actual.InvalidInputs)
+	assert.Nil(conceptIds, "invalid requests must not reach the driver")
+}
+
+func TestDuplicateIdentifiersAreLookedUpOnce(t *testing.T) {
+	assert := assert.New(t)
+	res, err := http.Get(concordanceURL + "?conceptId=2cdeb859-70df-3a0e-b125-f958366bea44&conceptId=http://api.ft.com/things/2cdeb859-70df-3a0e-b125-f958366bea44")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+	assert.Equal([]string{"2cdeb859-70df-3a0e-b125-f958366bea44"}, conceptIds)
+}
+
+func TestReturnBadRequestWhenTooManyIdentifiersAreRequested(t *testing.T) {
+	assert := assert.New(t)
+	res, err := http.Get(concordanceURL + "?authority=some-authority&identifierValue=1&identifierValue=2&identifierValue=3&identifierValue=4")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(400, res.StatusCode)
+
+	var actual APIError
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(CodeBatchSizeExceeded, actual.Code)
+}
+
+func TestConceptMetadataIsOnlyIncludedWhenAskedFor(t *testing.T) {
+	read := Concept{ID: bankOfTestConcept.ID, APIURL: bankOfTestConcept.APIURL, PrefLabel: "Bank of Test", Type: "Organisation"}
+	concept := Concept{ID: bankOfTestConcept.ID, APIURL: bankOfTestConcept.APIURL, PrefLabel: "Bank of Test", Type: "Organisation"}
+	identifier := Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}
+	mockConcordances = Concordances{Concordance: []Concordance{{Concept: read, Identifier: identifier}}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	tests := []struct {
+		include  string
+		expected Concept
+	}{
+		{include: "", expected: bankOfTestConcept},
+		{include: "&include=prefLabel", expected: Concept{ID: concept.ID, APIURL: concept.APIURL, PrefLabel: "Bank of Test"}},
+		{include: "&include=type", expected: Concept{ID: concept.ID, APIURL: concept.APIURL, Type: "Organisation"}},
+		{include: "&include=prefLabel,type", expected: concept},
+		{include: "&include=prefLabel&include=type", expected: concept},
+	}
+
+	for _, test := range tests {
+		t.Run(test.include, func(t *testing.T) {
+			res, err := http.Get(concordanceURL + "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E" + test.include)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 200, res.StatusCode)
+
+			var actual Concordances
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, []Concordance{{Concept: test.expected, Identifier: identifier}}, actual.Concordance)
+		})
+	}
+}
+
+func TestConceptTypeIsLeftOutWithoutLabels(t *testing.T) {
+	concept, err := neoConcept(neoReadStruct{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, "http://api.ft.com")
+	assert.NoError(t, err)
+	assert.Empty(t, concept.Type)
+}
+
+func TestReturnBadRequestGivenUnknownIncludeField(t *testing.T) {
+	assert := assert.New(t)
+	res, err := http.Get(concordanceURL + "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E&include=aliases")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(400, res.StatusCode)
+}
+
+func TestCanGroupConcordancesByConcept(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestFactset),
+		foundFor("5aba454b-3e31-31b9-bdeb-0caf83f62b44", managedLocationUPP),
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestUPP),
+	}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	// concepts are listed in the canonical order of their ids
+	expected := GroupedConcordances{Concepts: []ConceptIdentifiers{
+		{Concept: managedLocationConcept, Identifiers: []Identifier{managedLocationUPP.Identifier}},
+		{Concept: bankOfTestConcept, Identifiers: []Identifier{bankOfTestFactset.Identifier, bankOfTestUPP.Identifier}},
+	}}
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		contentType string
+	}{
+		{name: "format parameter", query: "&format=grouped", contentType: "application/json; charset=UTF-8"},
+		{name: "accept header", accept: "application/json;q=0.5, " + groupedMediaType, contentType: groupedMediaType + "; charset=UTF-8"},
+		{name: "format parameter overrides accept header", query: "&format=grouped", accept: "application/json", contentType: "application/json; charset=UTF-8"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&conceptId=5aba454b-3e31-31b9-bdeb-0caf83f62b44"+test.query, nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 200, res.StatusCode)
+			assert.Equal(t, test.contentType, res.Header.Get("Content-Type"))
+			assert.Equal(t, "Accept", res.Header.Get("Vary"))
+
+			var actual GroupedConcordances
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestIdentifiersFoundForTwoIdsOfOneConceptAreListedOnce(t *testing.T) {
+	const canonicalID, leafID = "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "2cdeb859-70df-3a0e-b125-f958366bea44"
+	mockConcordances = Concordances{Concordance: []Concordance{
+		foundFor(canonicalID, bankOfTestFactset),
+		foundFor(canonicalID, bankOfTestUPP),
+		foundFor(leafID, bankOfTestFactset),
+		foundFor(leafID, bankOfTestUPP),
+	}}
+	defer func() { mockConcordances = Concordances{} }()
+	query := concordanceURL + "?conceptId=" + canonicalID + "&conceptId=" + leafID
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []Concordance
+	}{
+		{name: "flat", expected: []Concordance{bankOfTestFactset, bankOfTestUPP}},
+		{name: "flat with input", query: "&include=input", expected: []Concordance{
+			foundFor(leafID, bankOfTestFactset),
+			foundFor(canonicalID, bankOfTestFactset),
+			foundFor(leafID, bankOfTestUPP),
+			foundFor(canonicalID, bankOfTestUPP),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := http.Get(query + test.query)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			var actual Concordances
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, test.expected, actual.Concordance)
+		})
+	}
+
+	for _, include := range []string{"", "&include=input"} {
+		t.Run("grouped"+include, func(t *testing.T) {
+			res, err := http.Get(query + "&format=grouped" + include)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			var actual GroupedConcordances
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, []ConceptIdentifiers{
+				{Concept: bankOfTestConcept, Identifiers: []Identifier{bankOfTestFactset.Identifier, bankOfTestUPP.Identifier}},
+			}, actual.Concepts)
+		})
+	}
+}
+
+func TestFlatFormatIsTheDefault(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	for _, query := range []string{"", "&format=flat"} {
+		res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115" + query)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "Accept", res.Header.Get("Vary"))
+
+		var actual Concordances
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+		assert.Equal(t, []Concordance{bankOfTestFactset}, actual.Concordance)
+	}
+}
+
+func TestReturnBadRequestGivenUnknownFormat(t *testing.T) {
+	res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&format=nested")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 400, res.StatusCode)
+}
+
+func TestCanTranslateIdentifiersBetweenAuthorities(t *testing.T) {
+	assert := assert.New(t)
+	translation := Translation{
+		From:    Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"},
+		To:      Identifier{Authority: "http://api.ft.com/system/LEI", IdentifierValue: "VNF516RB4DFV5NQ22UF0"},
+		Concept: Concept{ID: bankOfTestConcept.ID, APIURL: bankOfTestConcept.APIURL, PrefLabel: "Bank of Test"},
+	}
+	mockTranslations = []Translation{translation}
+	defer func() { mockTranslations = nil }()
+
+	res, err := http.Get(concordanceURL + "/translate?from=http://api.ft.com/system/FACTSET&to=http://api.ft.com/system/LEI&identifierValue=7IV872-E&identifierValue=ABCDEF-E&identifierValue=7IV872-E")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+	assert.Equal(cacheControlHeader, res.Header.Get("Cache-Control"))
+	assert.Equal([]string{"http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI", "7IV872-E", "ABCDEF-E"}, translateReq)
+
+	var actual Translations
+	assert.NoError(json.NewDecoder(res.Body).Decode(&actual))
+	translation.Concept.PrefLabel = ""
+	assert.Equal(Translations{Translations: []Translation{translation}, NotFound: []string{"ABCDEF-E"}}, actual)
+}
+
+func TestTranslateRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode ErrorCode
+	}{
+		{name: "MissingFrom", query: "?to=http://api.ft.com/system/LEI&identifierValue=7IV872-E", expectedCode: CodeAuthorityMissing},
+		{name: "MissingTo", query: "?from=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E", expectedCode: CodeAuthorityMissing},
+		{name: "MissingIdentifierValue", query: "?from=http://api.ft.com/system/FACTSET&to=http://api.ft.com/system/LEI", expectedCode: CodeInvalidInput},
+		{name: "InvalidIdentifierValue", query: "?from=http://api.ft.com/system/LEI&to=http://api.ft.com/system/FACTSET&identifierValue=lei", expectedCode: CodeInvalidInput},
+		{name: "BatchSizeExceeded", query: "?from=http://api.ft.com/system/FACTSET&to=http://api.ft.com/system/LEI&identifierValue=1&identifierValue=2&identifierValue=3&identifierValue=4", expectedCode: CodeBatchSizeExceeded},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := http.Get(concordanceURL + "/translate" + test.query)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 400, res.StatusCode)
+
+			var actual APIError
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			assert.Equal(t, test.expectedCode, actual.Code)
+		})
+	}
+}
+
+func TestTranslateReturnsInternalServerErrorWhenQueryFails(t *testing.T) {
+	readErr = errors.New("neo4j is down")
+	defer func() { readErr = nil }()
+
+	res, err := http.Get(concordanceURL + "/translate?from=http://api.ft.com/system/FACTSET&to=http://api.ft.com/system/LEI&identifierValue=7IV872-E")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 500, res.StatusCode)
+}
+
+func TestCanPageThroughConcordances(t *testing.T) {
+	assert := assert.New(t)
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}}
+	pagesReq = nil
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&conceptId=5aba454b-3e31-31b9-bdeb-0caf83f62b44&limit=2")
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+
+	var first Concordances
+	assert.NoError(json.NewDecoder(res.Body).Decode(&first))
+	assert.Equal([]Concordance{bankOfTestFactset, bankOfTestUPP}, first.Concordance)
+	assert.Empty(first.NotFound)
+	assert.NotEmpty(first.NextCursor)
+	assert.Equal([]string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "5aba454b-3e31-31b9-bdeb-0caf83f62b44"}, pageLookup.ConceptIDs)
+
+	res, err = http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&conceptId=5aba454b-3e31-31b9-bdeb-0caf83f62b44&limit=2&cursor=" + first.NextCursor)
+	assert.NoError(err)
+	defer res.Body.Close()
+	assert.EqualValues(200, res.StatusCode)
+
+	var second Concordances
+	assert.NoError(json.NewDecoder(res.Body).Decode(&second))
+	assert.Equal([]Concordance{managedLocationUPP}, second.Concordance)
+	assert.Empty(second.NextCursor)
+	assert.Equal([]Page{{Limit: 3}, {After: &PagePosition{
+		CanonicalUUID:  "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		Authority:      "UPP",
+		AuthorityValue: bankOfTestUPP.Identifier.IdentifierValue,
+		Input:          bankOfTestUPP.Input,
+	}, Limit: 3}}, pagesReq)
+}
+
+func TestPagesKeepTheOrderTheirCursorIsThePositionIn(t *testing.T) {
+	smartlogic := Concordance{Concept: bankOfTestConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/SMARTLOGIC", IdentifierValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}}
+	tme := Concordance{Concept: bankOfTestConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/FT-TME", IdentifierValue: "QmFuayBvZiBUZXN0-T04="}}
+	// in the order of pages SMARTLOGIC comes before TME, whose URI sorts first
+	mockConcordances = Concordances{Concordance: []Concordance{smartlogic, tme, bankOfTestUPP}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&limit=2")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	var actual Concordances
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(t, []Concordance{smartlogic, tme}, actual.Concordance)
+	assert.Equal(t, encodeCursor(positionOf(tme)), actual.NextCursor)
+}
+
+func TestCanPageThroughConcordancesOfSeveralAuthorities(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?authority=http://api.ft.com/system/FACTSET&identifierValue=7IV872-E&authority=http://api.ft.com/system/LEI&identifierValue=VNF516RB4DFV5NQ22UF0&limit=5")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+	assert.Equal(t, []AuthorityIdentifiers{
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValues: []string{"7IV872-E"}},
+		{Authority: "http://api.ft.com/system/LEI", IdentifierValues: []string{"VNF516RB4DFV5NQ22UF0"}},
+	}, pageLookup.Groups)
+
+	var actual Concordances
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(t, []Concordance{bankOfTestFactset}, actual.Concordance)
+	assert.Empty(t, actual.NextCursor)
+}
+
+func TestReturnBadRequestGivenInvalidPageParameters(t *testing.T) {
+	for _, query := range []string{"&limit=0", "&limit=ten", "&limit=1001", "&cursor=not-a-cursor", "&cursor=" + encodeCursor(PagePosition{})} {
+		res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115" + query)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(t, 400, res.StatusCode, query)
+
+		var body APIError
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, CodeInvalidParameter, body.Code, query)
+	}
+}
+
+func TestCanStreamConcordancesAsNDJSON(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []Concordance
+	}{
+		{name: "everything", expected: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}},
+		{name: "limited", query: "&limit=2", expected: []Concordance{bankOfTestFactset, bankOfTestUPP}},
+		{name: "from a cursor", query: "&cursor=" + encodeCursor(positionOf(bankOfTestFactset)), expected: []Concordance{bankOfTestUPP, managedLocationUPP}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"+test.query, nil)
+			req.Header.Set("Accept", ndjsonMediaType)
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, 200, res.StatusCode)
+			assert.Equal(t, ndjsonMediaType, res.Header.Get("Content-Type"))
+
+			var actual []Concordance
+			decoder := json.NewDecoder(res.Body)
+			for decoder.More() {
+				var concordance Concordance
+				assert.NoError(t, decoder.Decode(&concordance))
+				actual = append(actual, concordance)
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestStreamReturnsErrorsReadingTheFirstPage(t *testing.T) {
+	readErr = errors.New("failed")
+	defer func() { readErr = nil }()
+
+	req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", nil)
+	req.Header.Set("Accept", ndjsonMediaType)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 500, res.StatusCode)
+	assert.Equal(t, "application/json; charset=UTF-8", res.Header.Get("Content-Type"))
+}
+
+func TestCanExportAnAuthorityAsNDJSON(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}}
+	exportReq = nil
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "/export?authority=http://api.ft.com/system/UPP")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+	assert.Equal(t, ndjsonMediaType, res.Header.Get("Content-Type"))
+	assert.Equal(t, "no-store", res.Header.Get("Cache-Control"))
+
+	var actual []Concordance
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var concordance Concordance
+		assert.NoError(t, decoder.Decode(&concordance))
+		actual = append(actual, concordance)
+	}
+	assert.Equal(t, []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}, actual)
+	assert.Equal(t, []string{"", bankOfTestUPP.Identifier.IdentifierValue}, exportReq, "the second page should start after the last identifierValue of the first")
+}
+
+func TestExportOnlyIncludesTheConceptFieldsAskedFor(t *testing.T) {
+	read := bankOfTestUPP
+	read.Concept.PrefLabel = "Bank of Test"
+	read.Concept.Type = "Organisation"
+	mockConcordances = Concordances{Concordance: []Concordance{read}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	tests := []struct {
+		query    string
+		expected Concept
+	}{
+		{query: "", expected: bankOfTestConcept},
+		{query: "&include=prefLabel", expected: Concept{ID: bankOfTestConcept.ID, APIURL: bankOfTestConcept.APIURL, PrefLabel: "Bank of Test"}},
+		{query: "&include=prefLabel,type", expected: Concept{ID: bankOfTestConcept.ID, APIURL: bankOfTestConcept.APIURL, PrefLabel: "Bank of Test", Type: "Organisation"}},
+	}
+
+	for _, test := range tests {
+		res, err := http.Get(concordanceURL + "/export?authority=http://api.ft.com/system/UPP" + test.query)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(t, 200, res.StatusCode, test.query)
+
+		var actual Concordance
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual), test.query)
+		assert.Equal(t, test.expected, actual.Concept, test.query)
+	}
+}
+
+func TestCanExportAnAuthorityAsCSV(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset, bankOfTestUPP, managedLocationUPP}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	req, _ := http.NewRequest(http.MethodGet, concordanceURL+"/export?authority=http://api.ft.com/system/UPP&after="+bankOfTestFactset.Identifier.IdentifierValue, nil)
+	req.Header.Set("Accept", csvMediaType)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+	assert.Equal(t, "text/csv; charset=UTF-8", res.Header.Get("Content-Type"))
+
+	body, _ := ioutil.ReadAll(res.Body)
+	assert.Equal(t, "conceptId,apiUrl,authority,identifierValue\n"+
+		bankOfTestConcept.ID+","+bankOfTestConcept.APIURL+",http://api.ft.com/system/UPP,d56e7388-25cb-343e-aea9-8b512e28476e\n"+
+		managedLocationConcept.ID+","+managedLocationConcept.APIURL+",http://api.ft.com/system/UPP,5aba454b-3e31-31b9-bdeb-0caf83f62b44\n", string(body))
+}
+
+func TestExportResumesAfterTheLastConceptReceived(t *testing.T) {
+	first := Concordance{Concept: managedLocationConcept, Identifier: Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}}
+	// the value is held by two concepts, the export was interrupted after the first one
+	mockConcordances = Concordances{Concordance: []Concordance{foundFor("7IV872-E", bankOfTestFactset), foundFor("7IV872-E", first)}}
+	exportReq = nil
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "/export?authority=http://api.ft.com/system/FACTSET&after=7IV872-E&afterConceptId=" + managedLocationConcept.ID)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+
+	var actual []Concordance
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var concordance Concordance
+		assert.NoError(t, decoder.Decode(&concordance))
+		actual = append(actual, concordance)
+	}
+	assert.Equal(t, bankOfTestFactset, actual[0], "the rest of the concepts of the value should be exported first")
+	assert.Equal(t, []string{"7IV872-E"}, authorityValues)
+	assert.Equal(t, []string{"7IV872-E"}, exportReq, "the export should carry on after the value")
+}
+
+func TestExportRejectsMissingOrUnknownAuthorities(t *testing.T) {
+	tests := []struct {
+		query string
+		code  ErrorCode
+	}{
+		{query: "", code: CodeAuthorityMissing},
+		{query: "?authority=http://api.ft.com/system/UNKNOWN", code: CodeInvalidParameter},
+		{query: "?authority=http://api.ft.com/system/UPP&include=aliases", code: CodeInvalidParameter},
+		{query: "?authority=http://api.ft.com/system/UPP&afterConceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", code: CodeInvalidParameter},
+		{query: "?authority=http://api.ft.com/system/UPP&after=7IV872-E&afterConceptId=not-a-uuid", code: CodeInvalidParameter},
+	}
+
+	for _, test := range tests {
+		res, err := http.Get(concordanceURL + "/export" + test.query)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(t, 400, res.StatusCode, test.query)
+
+		var body APIError
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, test.code, body.Code, test.query)
+	}
+}
+
+func TestExportReturnsErrorsReadingTheFirstPage(t *testing.T) {
+	readErr = errors.New("failed")
+	defer func() { readErr = nil }()
+
+	res, err := http.Get(concordanceURL + "/export?authority=http://api.ft.com/system/UPP")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 500, res.StatusCode)
+}
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		accept     string
+		expected   string
+		acceptable bool
+	}{
+		{accept: "", expected: jsonMediaType, acceptable: true},
+		{accept: "*/*", expected: jsonMediaType, acceptable: true},
+		{accept: "text/*", expected: csvMediaType, acceptable: true},
+		{accept: "text/csv", expected: csvMediaType, acceptable: true},
+		{accept: "application/json;q=0.5, application/x-ndjson", expected: ndjsonMediaType, acceptable: true},
+		{accept: "text/csv;q=0.9, application/*;q=0.9", expected: jsonMediaType, acceptable: true},
+		{accept: "application/json;q=0, */*;q=0.1", expected: groupedMediaType, acceptable: true},
+		{accept: "text/html", acceptable: false},
+		{accept: "text/csv;q=0", acceptable: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			header := http.Header{}
+			if test.accept != "" {
+				header.Set("Accept", test.accept)
+			}
+			mediaType, acceptable := negotiateMediaType(header, concordancesMediaTypes...)
+			assert.Equal(t, test.acceptable, acceptable)
+			assert.Equal(t, test.expected, mediaType)
+		})
+	}
+}
+
+func TestCanGetConcordancesAsCSV(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset, bankOfTestUPP}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", nil)
+	req.Header.Set("Accept", "application/json;q=0.8, text/csv")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+	assert.Equal(t, "text/csv; charset=UTF-8", res.Header.Get("Content-Type"))
+	assert.Equal(t, "Accept", res.Header.Get("Vary"))
+
+	body, _ := ioutil.ReadAll(res.Body)
+	assert.Equal(t, "conceptId,apiUrl,authority,identifierValue\n"+
+		bankOfTestConcept.ID+","+bankOfTestConcept.APIURL+",http://api.ft.com/system/FACTSET,7IV872-E\n"+
+		bankOfTestConcept.ID+","+bankOfTestConcept.APIURL+",http://api.ft.com/system/UPP,d56e7388-25cb-343e-aea9-8b512e28476e\n", string(body))
+}
+
+func TestCSVOfNoConcordancesOnlyHasTheHeader(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", nil)
+	req.Header.Set("Accept", csvMediaType)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.EqualValues(t, 200, res.StatusCode)
+
+	body, _ := ioutil.ReadAll(res.Body)
+	assert.Equal(t, "conceptId,apiUrl,authority,identifierValue\n", string(body))
+}
+
+func TestReturnNotAcceptableGivenUnsupportedMediaTypes(t *testing.T) {
+	for _, path := range []string{"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "/export?authority=http://api.ft.com/system/UPP"} {
+		req, _ := http.NewRequest(http.MethodGet, concordanceURL+path, nil)
+		req.Header.Set("Accept", "application/xml, text/html")
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(t, 406, res.StatusCode, path)
+
+		var body APIError
+		assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, CodeNotAcceptable, body.Code, path)
+	}
+}
+
+func TestConcordancesAreSortedCanonically(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestUPP),
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", managedLocationUPP),
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestFactset),
+	}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115&conceptId=4534282c-d3ee-3595-9957-81a9293200f3&conceptId=0ca9aabb-4ec7-4c5c-bbb7-6e8e4dd8e3ed&include=input")
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	var actual Concordances
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	assert.Equal(t, []Concordance{
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", managedLocationUPP),
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestFactset),
+		foundFor("cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", bankOfTestUPP),
+	}, actual.Concordance)
+	assert.Equal(t, []string{"0ca9aabb-4ec7-4c5c-bbb7-6e8e4dd8e3ed", "4534282c-d3ee-3595-9957-81a9293200f3"}, actual.NotFound)
+}
+
+func TestETagDoesNotDependOnTheOrderConcordancesAreReadIn(t *testing.T) {
+	etagOf := func(concordances ...Concordance) string {
+		mockConcordances = Concordances{Concordance: concordances}
+		defer func() { mockConcordances = Concordances{} }()
+
+		res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115")
+		assert.NoError(t, err)
+		defer res.Body.Close()
+		assert.EqualValues(t, 200, res.StatusCode)
+		return res.Header.Get("ETag")
+	}
+
+	etag := etagOf(bankOfTestFactset, bankOfTestUPP)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, etag, etagOf(bankOfTestUPP, bankOfTestFactset))
+	assert.NotEqual(t, etag, etagOf(bankOfTestFactset))
+}
+
+func TestConditionalGetReturnsNotModified(t *testing.T) {
+	mockConcordances = Concordances{Concordance: []Concordance{bankOfTestFactset}}
+	defer func() { mockConcordances = Concordances{} }()
+
+	res, err := http.Get(concordanceURL + "?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115")
+	assert.NoError(t, err)
+	res.Body.Close()
+	etag := res.Header.Get("ETag")
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{name: "same etag", ifNoneMatch: etag, status: 304},
+		{name: "one of several etags", ifNoneMatch: `"0123", ` + etag, status: 304},
+		{name: "weak etag", ifNoneMatch: "W/" + etag, status: 304},
+		{name: "wildcard", ifNoneMatch: "*", status: 304},
+		{name: "other etag", ifNoneMatch: `"0123"`, status: 200},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, concordanceURL+"?conceptId=cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", nil)
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+			res, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+			assert.EqualValues(t, test.status, res.StatusCode)
+			assert.Equal(t, etag, res.Header.Get("ETag"))
+			assert.Equal(t, cacheControlHeader, res.Header.Get("Cache-Control"))
+
+			body, _ := ioutil.ReadAll(res.Body)
+			if test.status == 304 {
+				assert.Empty(t, body)
+			} else {
+				assert.NotEmpty(t, body)
+			}
+		})
+	}
+}
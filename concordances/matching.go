@@ -0,0 +1,32 @@
+package concordances
+
+// matchInputs assigns the concordances read for a set of conceptIds or identifierValues back to the input each one
+// was found for.
+func matchInputs(inputs []string, concordances []Concordance) map[string][]Concordance {
+	matched := map[string][]Concordance{}
+	for _, input := range inputs {
+		matched[input] = []Concordance{}
+	}
+	for _, c := range concordances {
+		if _, requested := matched[c.Input]; requested {
+			matched[c.Input] = append(matched[c.Input], c)
+		}
+	}
+	return matched
+}
+
+// mergeConcordances concatenates the given concordances, dropping duplicates read more than once.
+func mergeConcordances(groups ...[]Concordance) []Concordance {
+	merged := []Concordance{}
+	seen := map[Concordance]bool{}
+	for _, group := range groups {
+		for _, c := range group {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,495 @@
+package concordances
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ontology "github.com/Financial-Times/cm-graph-ontology/v2"
+)
+
+// CanonicalNode is a canonical concept along with its source concepts, in the format of the concept fixtures.
+// Properties holds every string field of the canonical concept, such as leiCode or iso31661.
+type CanonicalNode struct {
+	PrefUUID              string            `json:"prefUUID"`
+	PrefLabel             string            `json:"prefLabel"`
+	Type                  string            `json:"type"`
+	IsDeprecated          bool              `json:"isDeprecated"`
+	Properties            map[string]string `json:"-"`
+	SourceRepresentations []SourceNode      `json:"sourceRepresentations"`
+}
+
+// SourceNode is a source concept, stored as a leaf node EQUIVALENT_TO its canonical concept
+type SourceNode struct {
+	UUID           string `json:"uuid"`
+	Type           string `json:"type"`
+	Authority      string `json:"authority"`
+	AuthorityValue string `json:"authorityValue"`
+	IsDeprecated   bool   `json:"isDeprecated"`
+}
+
+// UnmarshalJSON reads the fields of the canonical concept, collecting all of its string fields as Properties
+func (n *CanonicalNode) UnmarshalJSON(data []byte) error {
+	type fields CanonicalNode
+	if err := json.Unmarshal(data, (*fields)(n)); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	n.Properties = map[string]string{}
+	for name, value := range raw {
+		if s, ok := value.(string); ok {
+			n.Properties[name] = s
+		}
+	}
+	return nil
+}
+
+// MemoryDriver answers lookups from canonical concepts held in memory, with the same semantics as CypherDriver.
+// It evaluates the resolvers of the registry natively, from where they declare the identifiers are found.
+// Nodes are labelled with their type and all of its parent types in the ontology, as when written to Neo4j.
+type MemoryDriver struct {
+	publicAPIURL string
+	resolvers    *ResolverRegistry
+	concepts     []*memoryConcept
+	leaves       map[string]*memoryLeaf
+}
+
+type memoryConcept struct {
+	node   CanonicalNode
+	labels []string
+	leaves []*memoryLeaf
+}
+
+type memoryLeaf struct {
+	node      SourceNode
+	labels    []string
+	canonical *memoryConcept
+}
+
+// NewMemoryDriver holds the canonical concepts in memory. As when they are written to Neo4j a concept replaces
+// a previous one of the same prefUUID, and a source concept is only EQUIVALENT_TO the last canonical concept listing it.
+func NewMemoryDriver(publicAPIURL string, nodes ...CanonicalNode) (*MemoryDriver, error) {
+	_, err := url.ParseRequestURI(publicAPIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	concepts := map[string]*memoryConcept{}
+	leaves := map[string]*memoryLeaf{}
+	for _, node := range nodes {
+		labels, err := nodeLabels(node.Type)
+		if err != nil {
+			return nil, fmt.Errorf("labelling concept %s: %w", node.PrefUUID, err)
+		}
+		concept := &memoryConcept{node: node, labels: labels}
+		concepts[node.PrefUUID] = concept
+		for _, source := range node.SourceRepresentations {
+			labels, err := nodeLabels(source.Type)
+			if err != nil {
+				return nil, fmt.Errorf("labelling source concept %s: %w", source.UUID, err)
+			}
+			leaves[source.UUID] = &memoryLeaf{node: source, labels: labels, canonical: concept}
+		}
+	}
+
+	md := &MemoryDriver{publicAPIURL: publicAPIURL, resolvers: defaultResolvers, leaves: map[string]*memoryLeaf{}}
+	for uuid, leaf := range leaves {
+		if concepts[leaf.canonical.node.PrefUUID] == leaf.canonical {
+			md.leaves[uuid] = leaf
+			leaf.canonical.leaves = append(leaf.canonical.leaves, leaf)
+		}
+	}
+	for _, concept := range concepts {
+		sort.Slice(concept.leaves, func(i, j int) bool { return concept.leaves[i].node.UUID < concept.leaves[j].node.UUID })
+		md.concepts = append(md.concepts, concept)
+	}
+	sort.Slice(md.concepts, func(i, j int) bool { return md.concepts[i].node.PrefUUID < md.concepts[j].node.PrefUUID })
+	return md, nil
+}
+
+// LoadMemoryDriver holds in memory the canonical concepts of every JSON file of the directory
+func LoadMemoryDriver(publicAPIURL string, dir string) (*MemoryDriver, error) {
+	nodes, err := loadCanonicalNodes(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryDriver(publicAPIURL, nodes...)
+}
+
+// loadCanonicalNodes reads the canonical concepts of every JSON file of the directory
+func loadCanonicalNodes(dir string) ([]CanonicalNode, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []CanonicalNode
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading concept file %s: %w", file, err)
+		}
+		var node CanonicalNode
+		if err := json.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("decoding concept file %s: %w", file, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// nodeLabels are the labels of a node of the type: the type and all of its parent types in the ontology
+func nodeLabels(conceptType string) ([]string, error) {
+	typeURIs, err := ontology.TypeURIs([]string{conceptType})
+	if err != nil {
+		return nil, fmt.Errorf("finding the parent types of %q: %w", conceptType, err)
+	}
+	labels := make([]string, 0, len(typeURIs))
+	for _, typeURI := range typeURIs {
+		labels = append(labels, typeURI[strings.LastIndex(typeURI, "/")+1:])
+	}
+	return labels, nil
+}
+
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConnectivity always succeeds as there is no datastore to connect to
+func (md *MemoryDriver) CheckConnectivity(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// ReadByConceptID reads the identifiers of the concepts, restricted to the given authority URIs unless there are none
+func (md *MemoryDriver) ReadByConceptID(ctx context.Context, identifiers []string, authorities []string) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+	selection := md.resolvers.selectForConceptIDs(authorities)
+	return concordancesOfRows(md.conceptIDRows(identifiers, selection), md.publicAPIURL)
+}
+
+func (md *MemoryDriver) ReadByAuthority(ctx context.Context, authority string, identifierValues []string) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+	name, known := AuthorityFromURI(authority)
+	if !known {
+		return Concordances{}, false, nil
+	}
+	return concordancesOfRows(md.authorityRows(md.resolvers.Resolver(name), name, identifierValues), md.publicAPIURL)
+}
+
+// ReadByAuthorities reads the identifierValues of every group and merges their results
+func (md *MemoryDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	var read [][]Concordance
+	for _, group := range groups {
+		groupConcordances, groupFound, err := md.ReadByAuthority(ctx, group.Authority, group.IdentifierValues)
+		if err != nil {
+			return Concordances{}, false, err
+		}
+		if groupFound {
+			read = append(read, groupConcordances.Concordance)
+		}
+	}
+
+	if len(read) == 0 {
+		return Concordances{}, false, nil
+	}
+	return Concordances{Concordance: mergeConcordances(read...)}, true, nil
+}
+
+// Translate reads the identifiers of the to authority of the concepts identified by the identifierValues of the from
+// authority
+func (md *MemoryDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return []Translation{}, false, err
+	}
+	fromName, fromKnown := AuthorityFromURI(from)
+	toName, toKnown := AuthorityFromURI(to)
+	if !fromKnown || !toKnown {
+		return []Translation{}, false, nil
+	}
+
+	translations = []Translation{}
+	seen := map[string]bool{}
+	toResolver := md.resolvers.Resolver(toName)
+	for _, source := range md.authorityRows(md.resolvers.Resolver(fromName), fromName, identifierValues) {
+		p, found := md.leaves[source.CanonicalUUID]
+		if !found {
+			continue
+		}
+		for _, row := range md.nativeConceptIDRows(toResolver, p, []string{toName}) {
+			key := source.AuthorityValue + "\x00" + row.CanonicalUUID + "\x00" + row.Authority + "\x00" + row.AuthorityValue
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			concept, err := neoConcept(row, md.publicAPIURL)
+			if err != nil {
+				return []Translation{}, false, fmt.Errorf("transforming result from datastore: %w", err)
+			}
+			translations = append(translations, Translation{
+				From:    Identifier{Authority: from, IdentifierValue: source.AuthorityValue},
+				To:      Identifier{Authority: to, IdentifierValue: row.AuthorityValue},
+				Concept: concept,
+			})
+		}
+	}
+	return translations, len(translations) > 0, nil
+}
+
+// ReadPage reads a single page of the concordances of the lookup, in the order of canonical concept and identifier
+func (md *MemoryDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+
+	var rows []neoReadStruct
+	if len(lookup.Groups) == 0 {
+		rows = md.conceptIDRows(lookup.ConceptIDs, md.resolvers.selectForConceptIDs(lookup.Authorities))
+	} else {
+		for _, group := range lookup.Groups {
+			if name, known := AuthorityFromURI(group.Authority); known {
+				rows = append(rows, md.authorityRows(md.resolvers.Resolver(name), name, group.IdentifierValues)...)
+			}
+		}
+	}
+
+	return concordancesOfRows(pageOfRows(rows, page), md.publicAPIURL)
+}
+
+// ExportPage reads the concordances of the first limit identifierValues of the authority greater than after,
+// in the order of identifierValue. next is the identifierValue the following page starts after, empty on the last page.
+func (md *MemoryDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, "", err
+	}
+	name, known := AuthorityFromURI(authority)
+	if !known {
+		return Concordances{}, "", nil
+	}
+	resolver := md.resolvers.Resolver(name)
+	if resolver.ValuesCypher == "" {
+		return Concordances{}, "", nil
+	}
+
+	var values []string
+	for _, value := range md.authorityValues(resolver, name) {
+		if value > after && len(values) < limit {
+			values = append(values, value)
+		}
+	}
+	rows := md.authorityRows(resolver, name, values)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].AuthorityValue != rows[j].AuthorityValue {
+			return rows[i].AuthorityValue < rows[j].AuthorityValue
+		}
+		return rows[i].CanonicalUUID < rows[j].CanonicalUUID
+	})
+
+	concordances, _, err = concordancesOfRows(rows, md.publicAPIURL)
+	if err != nil {
+		return Concordances{}, "", err
+	}
+	concordances, next = exportedConcordances(concordances, limit)
+	return concordances, next, nil
+}
+
+// concordancesOfRows transforms the rows as CypherDriver does, they are found if there is any
+func concordancesOfRows(rows []neoReadStruct, publicAPIURL string) (Concordances, bool, error) {
+	if len(rows) == 0 {
+		return Concordances{}, false, nil
+	}
+	concordances, err := neoReadStructToConcordances(rows, publicAPIURL)
+	if err != nil {
+		return Concordances{}, false, fmt.Errorf("transforming result from datastore: %w", err)
+	}
+	return concordances, true, nil
+}
+
+// conceptIDRows reads what is selected for the leaf nodes whose uuid is one of the identifiers
+func (md *MemoryDriver) conceptIDRows(identifiers []string, selection conceptIDSelection) []neoReadStruct {
+	var rows []neoReadStruct
+	for _, id := range identifiers {
+		p, found := md.leaves[id]
+		if !found {
+			continue
+		}
+		if selection.leafNodes {
+			rows = append(rows, md.nativeConceptIDRows(leafNodeResolver, p, selection.leafAuthorities)...)
+		}
+		for _, resolver := range selection.resolvers {
+			rows = append(rows, md.nativeConceptIDRows(resolver, p, nil)...)
+		}
+	}
+	return rows
+}
+
+// nativeConceptIDRows reads the identifiers the resolver finds for the leaf node p,
+// the ones of the leaf nodes are restricted to leafAuthorities unless it is nil
+func (md *MemoryDriver) nativeConceptIDRows(resolver AuthorityResolver, p *memoryLeaf, leafAuthorities []string) []neoReadStruct {
+	canonical := p.canonical
+	var rows []neoReadStruct
+	switch {
+	case resolver.leafUUID:
+		for _, leaf := range canonical.leaves {
+			rows = append(rows, memoryRow(canonical, p.node.UUID, p.node.UUID, memoryInputType(p), resolver.Authority, leaf.node.UUID))
+		}
+	case resolver.property != "":
+		value := canonical.node.Properties[resolver.property]
+		if value != "" && hasLabel(p.labels, resolver.sourceLabel) {
+			rows = append(rows, memoryRow(canonical, p.node.UUID, p.node.UUID, memoryInputType(p), resolver.Authority, value))
+		}
+	case resolver.Authority == leafNodeResolver.Authority:
+		for _, leaf := range canonical.leaves {
+			if leafAuthorities == nil || containsString(leafAuthorities, leaf.node.Authority) {
+				rows = append(rows, memoryRow(canonical, p.node.UUID, p.node.UUID, memoryInputType(p), leaf.node.Authority, leaf.node.AuthorityValue))
+			}
+		}
+	}
+	return rows
+}
+
+// authorityRows reads the concepts the resolver finds holding one of the identifierValues of the authority
+func (md *MemoryDriver) authorityRows(resolver AuthorityResolver, authority string, identifierValues []string) []neoReadStruct {
+	var rows []neoReadStruct
+	switch {
+	case resolver.leafUUID:
+		for _, value := range identifierValues {
+			if p, found := md.leaves[value]; found {
+				rows = append(rows, memoryRow(p.canonical, p.node.UUID, value, memoryInputType(p), authority, value))
+			}
+		}
+	case resolver.property != "":
+		for _, canonical := range md.concepts {
+			value := canonical.node.Properties[resolver.property]
+			if value != "" && hasLabel(canonical.labels, resolver.canonicalLabel) && containsString(identifierValues, value) {
+				rows = append(rows, memoryRow(canonical, "", value, InputTypeCanonical, authority, value))
+			}
+		}
+	case resolver.Authority == leafNodeResolver.Authority:
+		for _, canonical := range md.concepts {
+			for _, p := range canonical.leaves {
+				if p.node.Authority == authority && containsString(identifierValues, p.node.AuthorityValue) {
+					rows = append(rows, memoryRow(canonical, p.node.UUID, p.node.AuthorityValue, memoryInputType(p), authority, p.node.AuthorityValue))
+				}
+			}
+		}
+	}
+	return rows
+}
+
+// authorityValues are, in order, all the identifierValues of the authority the resolver finds a concept for
+func (md *MemoryDriver) authorityValues(resolver AuthorityResolver, authority string) []string {
+	distinct := map[string]bool{}
+	for _, canonical := range md.concepts {
+		switch {
+		case resolver.leafUUID:
+			for _, p := range canonical.leaves {
+				distinct[p.node.UUID] = true
+			}
+		case resolver.property != "":
+			if value := canonical.node.Properties[resolver.property]; value != "" && hasLabel(canonical.labels, resolver.canonicalLabel) {
+				distinct[value] = true
+			}
+		case resolver.Authority == leafNodeResolver.Authority:
+			for _, p := range canonical.leaves {
+				if p.node.Authority == authority {
+					distinct[p.node.AuthorityValue] = true
+				}
+			}
+		}
+	}
+
+	values := make([]string, 0, len(distinct))
+	for value := range distinct {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func memoryRow(canonical *memoryConcept, uuid string, input string, inputType string, authority string, authorityValue string) neoReadStruct {
+	return neoReadStruct{
+		CanonicalUUID:  canonical.node.PrefUUID,
+		UUID:           uuid,
+		Types:          canonical.labels,
+		PrefLabel:      canonical.node.PrefLabel,
+		IsDeprecated:   canonical.node.IsDeprecated,
+		Input:          input,
+		InputType:      inputType,
+		Authority:      authority,
+		AuthorityValue: authorityValue,
+	}
+}
+
+// memoryInputType tells what the leaf node p matched for a requested identifier is, as inputTypeOfP does
+func memoryInputType(p *memoryLeaf) string {
+	switch {
+	case p.node.UUID == p.canonical.node.PrefUUID:
+		return InputTypeCanonical
+	case p.node.IsDeprecated:
+		return InputTypeDeprecated
+	default:
+		return InputTypeLeaf
+	}
+}
+
+// pageOfRows is the window of the page over the distinct rows, in the order of a page query
+func pageOfRows(rows []neoReadStruct, page Page) []neoReadStruct {
+	rows = distinctRows(rows)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rowPosition(rows[i]).before(rowPosition(rows[j]))
+	})
+	start := 0
+	if page.After != nil {
+		start = sort.Search(len(rows), func(i int) bool {
+			return page.After.before(rowPosition(rows[i]))
+		})
+	}
+	end := min(start+page.Limit, len(rows))
+	return rows[start:end]
+}
+
+func rowPosition(row neoReadStruct) PagePosition {
+	return PagePosition{CanonicalUUID: row.CanonicalUUID, Authority: row.Authority, AuthorityValue: row.AuthorityValue, Input: row.Input}
+}
+
+// distinctRows removes the rows equal to a previous one but for the uuid of the node they were read from
+func distinctRows(rows []neoReadStruct) []neoReadStruct {
+	distinct := []neoReadStruct{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		key := fmt.Sprintf("%s\x00%v\x00%s\x00%t\x00%s\x00%s\x00%s\x00%s", row.CanonicalUUID, row.Types, row.PrefLabel, row.IsDeprecated, row.Input, row.InputType, row.Authority, row.AuthorityValue)
+		if !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, row)
+		}
+	}
+	return distinct
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
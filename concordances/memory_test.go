@@ -0,0 +1,246 @@
+package concordances
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	bankOfTestID   = "http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"
+	bankOfTestLeaf = "2cdeb859-70df-3a0e-b125-f958366bea44"
+	romaniaID      = "http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44"
+)
+
+func loadFixtures(t *testing.T) *MemoryDriver {
+	driver, err := LoadMemoryDriver("http://api.ft.com", "./fixtures")
+	assert.NoError(t, err)
+	return driver
+}
+
+// identifiers lists the concept and identifier of the concordances in order
+func identifiers(concordances []Concordance) []string {
+	var ids []string
+	for _, c := range concordances {
+		ids = append(ids, c.Concept.ID+" "+c.Identifier.Authority+" "+c.Identifier.IdentifierValue)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestMemoryDriverReadByConceptID(t *testing.T) {
+	driver := loadFixtures(t)
+
+	concordances, found, err := driver.ReadByConceptID(context.Background(), []string{bankOfTestLeaf}, []string{
+		"http://api.ft.com/system/FACTSET",
+		"http://api.ft.com/system/FT-TME",
+		"http://api.ft.com/system/LEI",
+		"http://api.ft.com/system/UPP",
+	})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{
+		bankOfTestID + " http://api.ft.com/system/FACTSET 7IV872-E",
+		bankOfTestID + " http://api.ft.com/system/FT-TME QmFuayBvZiBUZXN0-T04=",
+		bankOfTestID + " http://api.ft.com/system/LEI VNF516RB4DFV5NQ22UF0",
+		bankOfTestID + " http://api.ft.com/system/UPP 2cdeb859-70df-3a0e-b125-f958366bea44",
+		bankOfTestID + " http://api.ft.com/system/UPP cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		bankOfTestID + " http://api.ft.com/system/UPP d56e7388-25cb-343e-aea9-8b512e28476e",
+	}, identifiers(concordances.Concordance))
+	for _, c := range concordances.Concordance {
+		assert.Equal(t, bankOfTestLeaf, c.Input)
+		assert.Equal(t, InputTypeLeaf, c.InputType)
+		assert.Equal(t, "Bank of Test", c.Concept.PrefLabel)
+		assert.Equal(t, "Organisation", c.Concept.Type)
+	}
+}
+
+func TestMemoryDriverReadByConceptIDNotFound(t *testing.T) {
+	driver := loadFixtures(t)
+
+	tests := []struct {
+		name        string
+		ids         []string
+		authorities []string
+	}{
+		{name: "unknown conceptId", ids: []string{"00000000-0000-0000-0000-000000000000"}},
+		{name: "unknown authority", ids: []string{bankOfTestLeaf}, authorities: []string{"http://api.ft.com/system/UNKNOWN"}},
+		{name: "authority without identifier", ids: []string{bankOfTestLeaf}, authorities: []string{"http://api.ft.com/system/ISO-3166-1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			concordances, found, err := driver.ReadByConceptID(context.Background(), test.ids, test.authorities)
+			assert.NoError(t, err)
+			assert.False(t, found)
+			assert.Empty(t, concordances.Concordance)
+		})
+	}
+}
+
+func TestMemoryDriverReadByAuthority(t *testing.T) {
+	driver := loadFixtures(t)
+
+	tests := []struct {
+		authority string
+		value     string
+		conceptID string
+		inputType string
+	}{
+		{authority: "http://api.ft.com/system/FACTSET", value: "7IV872-E", conceptID: bankOfTestID, inputType: InputTypeLeaf},
+		{authority: "http://api.ft.com/system/LEI", value: "VNF516RB4DFV5NQ22UF0", conceptID: bankOfTestID, inputType: InputTypeCanonical},
+		{authority: "http://api.ft.com/system/ISO-3166-1", value: "RO", conceptID: romaniaID, inputType: InputTypeCanonical},
+		{authority: "http://api.ft.com/system/UPP", value: "4411b761-e632-30e7-855c-06aeca76c48d", conceptID: romaniaID, inputType: InputTypeLeaf},
+		{authority: "http://api.ft.com/system/ISIN", value: "GB00BTST1234", conceptID: "http://api.ft.com/things/6f3bd2a1-8a47-4e8e-9b1c-2d5e0c7f4a93", inputType: InputTypeCanonical},
+	}
+	for _, test := range tests {
+		t.Run(test.authority, func(t *testing.T) {
+			concordances, found, err := driver.ReadByAuthority(context.Background(), test.authority, []string{test.value, "unknown"})
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, []string{test.conceptID + " " + test.authority + " " + test.value}, identifiers(concordances.Concordance))
+			assert.Equal(t, test.value, concordances.Concordance[0].Input)
+			assert.Equal(t, test.inputType, concordances.Concordance[0].InputType)
+		})
+	}
+}
+
+func TestMemoryDriverTranslate(t *testing.T) {
+	driver := loadFixtures(t)
+
+	translations, found, err := driver.Translate(context.Background(), "http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI", []string{"7IV872-E", "BTST12-S"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, translations, 1)
+	assert.Equal(t, Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}, translations[0].From)
+	assert.Equal(t, Identifier{Authority: "http://api.ft.com/system/LEI", IdentifierValue: "VNF516RB4DFV5NQ22UF0"}, translations[0].To)
+	assert.Equal(t, bankOfTestID, translations[0].Concept.ID)
+}
+
+func TestMemoryDriverReadPageCoversEveryConcordanceOnce(t *testing.T) {
+	driver := loadFixtures(t)
+	lookup := Lookup{ConceptIDs: []string{bankOfTestLeaf, "4411b761-e632-30e7-855c-06aeca76c48d"}}
+
+	all, _, err := driver.ReadByConceptID(context.Background(), lookup.ConceptIDs, nil)
+	assert.NoError(t, err)
+
+	var paged []Concordance
+	var after *PagePosition
+	for {
+		page, found, err := driver.ReadPage(context.Background(), lookup, Page{After: after, Limit: 2})
+		assert.NoError(t, err)
+		if !found {
+			break
+		}
+		assert.LessOrEqual(t, len(page.Concordance), 2)
+		paged = append(paged, page.Concordance...)
+		last := positionOf(page.Concordance[len(page.Concordance)-1])
+		after = &last
+	}
+	assert.Equal(t, identifiers(all.Concordance), identifiers(paged))
+}
+
+func TestMemoryDriverExportPage(t *testing.T) {
+	driver := loadFixtures(t)
+
+	var values []string
+	after := ""
+	for {
+		page, next, err := driver.ExportPage(context.Background(), "http://api.ft.com/system/FT-TME", after, 2)
+		assert.NoError(t, err)
+		for _, c := range page.Concordance {
+			assert.Empty(t, c.Input)
+			values = append(values, c.Identifier.IdentifierValue)
+		}
+		if next == "" {
+			break
+		}
+		assert.Equal(t, values[len(values)-1], next)
+		after = next
+	}
+
+	assert.True(t, sort.StringsAreSorted(values))
+	assert.Contains(t, values, "QmFuayBvZiBUZXN0-T04=")
+	assert.Contains(t, values, "UGFydHkgcGVvcGxl-QnJhbmRz")
+}
+
+func TestMemoryDriverSourceConceptBelongsToTheLastCanonicalConcept(t *testing.T) {
+	source := SourceNode{UUID: "9c7a6b1e-5d4c-4b3a-8f2e-1d0c9b8a7f6e", Type: "Organisation", Authority: "FACTSET", AuthorityValue: "000001-E"}
+	driver, err := NewMemoryDriver("http://api.ft.com",
+		CanonicalNode{PrefUUID: "11111111-1111-1111-1111-111111111111", Type: "Organisation", SourceRepresentations: []SourceNode{source}},
+		CanonicalNode{PrefUUID: "22222222-2222-2222-2222-222222222222", Type: "Organisation", SourceRepresentations: []SourceNode{source}},
+	)
+	assert.NoError(t, err)
+
+	concordances, found, err := driver.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"000001-E"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"http://api.ft.com/things/22222222-2222-2222-2222-222222222222 http://api.ft.com/system/FACTSET 000001-E"}, identifiers(concordances.Concordance))
+}
+
+func TestMemoryDriverLabelsNodesWithTheParentTypesOfTheirType(t *testing.T) {
+	driver, err := NewMemoryDriver("http://api.ft.com", CanonicalNode{
+		PrefUUID:              "11111111-1111-1111-1111-111111111111",
+		Type:                  "PublicCompany",
+		SourceRepresentations: []SourceNode{{UUID: "11111111-1111-1111-1111-111111111111", Type: "PublicCompany", Authority: "FACTSET", AuthorityValue: "000001-E"}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Thing", "Concept", "Organisation", "Company", "PublicCompany"}, driver.concepts[0].labels)
+	assert.Equal(t, driver.concepts[0].labels, driver.leaves["11111111-1111-1111-1111-111111111111"].labels)
+}
+
+func TestEveryRegisteredAuthorityResolvesInMemoryDriver(t *testing.T) {
+	const canonicalUUID = "11111111-1111-1111-1111-111111111111"
+	for _, resolver := range defaultResolvers.resolvers {
+		t.Run(resolver.Authority, func(t *testing.T) {
+			uri, _ := AuthorityToURI(resolver.Authority)
+			node := CanonicalNode{PrefUUID: canonicalUUID, Type: "Concept", Properties: map[string]string{}}
+			source := SourceNode{UUID: canonicalUUID, Type: "Concept"}
+			value := canonicalUUID
+			if resolver.property != "" {
+				node.Type, source.Type = resolver.canonicalLabel, resolver.sourceLabel
+				value = "value of " + resolver.Authority
+				node.Properties[resolver.property] = value
+			}
+			node.SourceRepresentations = []SourceNode{source}
+			driver, err := NewMemoryDriver("http://api.ft.com", node)
+			assert.NoError(t, err)
+
+			byValue, found, err := driver.ReadByAuthority(context.Background(), uri, []string{value})
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, []string{thingURL + canonicalUUID + " " + uri + " " + value}, identifiers(byValue.Concordance))
+
+			byConceptID, found, err := driver.ReadByConceptID(context.Background(), []string{canonicalUUID}, []string{uri})
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, identifiers(byValue.Concordance), identifiers(byConceptID.Concordance))
+		})
+	}
+}
+
+func TestNewMemoryDriverRejectsUnknownTypes(t *testing.T) {
+	_, err := NewMemoryDriver("http://api.ft.com", CanonicalNode{PrefUUID: "11111111-1111-1111-1111-111111111111", Type: "NotAType"})
+	assert.Error(t, err)
+}
+
+func TestNewMemoryDriverRejectsInvalidURL(t *testing.T) {
+	_, err := NewMemoryDriver("not a url")
+	assert.Error(t, err)
+}
+
+func TestMemoryDriverServesTheHandler(t *testing.T) {
+	hh := NewHTTPHandler(logger.NewUPPLogger("test-service", "panic"), loadFixtures(t), "max-age=360", 3, 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/concordances?authority=http://api.ft.com/system/LEI&identifierValue=VNF516RB4DFV5NQ22UF0", nil)
+	rec := httptest.NewRecorder()
+	hh.GetConcordances(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), bankOfTestID)
+}
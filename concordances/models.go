@@ -1,20 +1,41 @@
 package concordances
 
-// Concordances is a list of concordances wrapped like this for parity in the JSON currently produced
+// Concordances is a list of concordances wrapped like this for parity in the JSON currently produced.
+// NotFound lists the requested conceptIds and identifierValues no concordance was found for.
+// NextCursor is only set on a page of concordances followed by another one.
 type Concordances struct {
 	Concordance []Concordance `json:"concordances,omitempty"`
+	NotFound    []string      `json:"notFound,omitempty"`
+	NextCursor  string        `json:"nextCursor,omitempty"`
 }
 
-// Concept is a concept equivilant to a thing
+// Concept is a concept equivilant to a thing.
+// PrefLabel and Type, the most specific ontology type of the concept, are only returned when asked for.
 type Concept struct {
-	ID     string `json:"id"`
-	APIURL string `json:"apiUrl"`
+	ID           string `json:"id"`
+	APIURL       string `json:"apiUrl"`
+	PrefLabel    string `json:"prefLabel,omitempty"`
+	Type         string `json:"type,omitempty"`
+	IsDeprecated bool   `json:"isDeprecated,omitempty"`
 }
 
-// Concordance is the structure used for the people API
+// Input types tell what the requested identifier a concordance was found for identifies
+const (
+	// InputTypeCanonical is the prefUUID of the canonical concept, or an identifier stored on it
+	InputTypeCanonical = "canonical"
+	// InputTypeLeaf is a source concept concorded to the canonical concept
+	InputTypeLeaf = "leaf"
+	// InputTypeDeprecated is a deprecated source concept, which should be replaced by the canonical concept
+	InputTypeDeprecated = "deprecated"
+)
+
+// Concordance is the structure used for the people API.
+// Input is the requested conceptId UUID or identifierValue the concordance was found for.
 type Concordance struct {
 	Concept    Concept    `json:"concept,omitempty"`
 	Identifier Identifier `json:"identifier,omitempty"`
+	Input      string     `json:"input,omitempty"`
+	InputType  string     `json:"inputType,omitempty"`
 }
 
 // Identifier identifies the concept with alternative identity
@@ -23,10 +44,122 @@ type Identifier struct {
 	IdentifierValue string `json:"identifierValue"`
 }
 
+// GroupedConcordances is the alternative shape of Concordances listing every canonical concept once,
+// along with all of its identifiers
+type GroupedConcordances struct {
+	Concepts   []ConceptIdentifiers `json:"concepts,omitempty"`
+	NotFound   []string             `json:"notFound,omitempty"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
+
+// ConceptIdentifiers is a canonical concept with the identifiers it is concorded to
+type ConceptIdentifiers struct {
+	Concept     Concept      `json:"concept"`
+	Identifiers []Identifier `json:"identifiers"`
+}
+
+// groupByConcept groups the concordances under their canonical concepts, in the order the concepts first appear.
+// An identifier found for several requested identifiers of the same concept is listed once.
+func (c Concordances) groupByConcept() GroupedConcordances {
+	grouped := GroupedConcordances{NotFound: c.NotFound, NextCursor: c.NextCursor}
+	index := map[Concept]int{}
+	seen := map[Concordance]bool{}
+	for _, concordance := range c.Concordance {
+		key := Concordance{Concept: concordance.Concept, Identifier: concordance.Identifier}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		i, found := index[concordance.Concept]
+		if !found {
+			i = len(grouped.Concepts)
+			index[concordance.Concept] = i
+			grouped.Concepts = append(grouped.Concepts, ConceptIdentifiers{Concept: concordance.Concept})
+		}
+		grouped.Concepts[i].Identifiers = append(grouped.Concepts[i].Identifiers, concordance.Identifier)
+	}
+	return grouped
+}
+
+// Lookup is what a page of concordances is read for, either conceptIds whose identifiers are optionally
+// restricted to the Authorities URIs, or identifierValues grouped by authority
+type Lookup struct {
+	ConceptIDs  []string
+	Authorities []string
+	Groups      []AuthorityIdentifiers
+}
+
+// Page is a window of the concordances of a lookup, ordered by canonical concept and identifier
+type Page struct {
+	// After is the position of the last concordance of the previous page, nil for the first page
+	After *PagePosition
+	Limit int
+}
+
+// PagePosition is where a concordance is in the order of pages: by canonical concept UUID, authority name in the
+// ontology systems, identifierValue and input
+type PagePosition struct {
+	CanonicalUUID  string
+	Authority      string
+	AuthorityValue string
+	Input          string
+}
+
+// BulkRequest is the body of a bulk concordances lookup
+type BulkRequest struct {
+	ConceptIDs  []string               `json:"conceptIds,omitempty"`
+	Authorities []AuthorityIdentifiers `json:"authorities,omitempty"`
+}
+
+// AuthorityIdentifiers groups identifier values issued by the same authority
+type AuthorityIdentifiers struct {
+	Authority        string   `json:"authority"`
+	IdentifierValues []string `json:"identifierValues"`
+}
+
+// BulkResponse holds the concordances found for every identifier of a bulk request, in request order
+type BulkResponse struct {
+	Results []BulkResult `json:"results"`
+}
+
+// BulkResult is the concordances found for a single requested conceptId or authority identifierValue
+type BulkResult struct {
+	ConceptID       string        `json:"conceptId,omitempty"`
+	Authority       string        `json:"authority,omitempty"`
+	IdentifierValue string        `json:"identifierValue,omitempty"`
+	Concordances    []Concordance `json:"concordances"`
+}
+
+func (r BulkRequest) size() int {
+	size := len(r.ConceptIDs)
+	for _, group := range r.Authorities {
+		size += len(group.IdentifierValues)
+	}
+	return size
+}
+
+// Translation pairs a requested identifier of one authority with an identifier of another authority of the same concept
+type Translation struct {
+	From    Identifier `json:"from"`
+	To      Identifier `json:"to"`
+	Concept Concept    `json:"concept"`
+}
+
+// Translations is the response of a translation, NotFound lists the requested identifierValues without any translation
+type Translations struct {
+	Translations []Translation `json:"translations"`
+	NotFound     []string      `json:"notFound,omitempty"`
+}
+
 type neoReadStruct struct {
+	SourceValue    string   `json:"sourceValue"`
 	CanonicalUUID  string   `json:"canonicalUUID"`
 	UUID           string   `json:"UUID"`
 	Types          []string `json:"types"`
+	PrefLabel      string   `json:"prefLabel"`
+	IsDeprecated   bool     `json:"isDeprecated"`
+	Input          string   `json:"input"`
+	InputType      string   `json:"inputType"`
 	Authority      string   `json:"authority"`
 	AuthorityValue string   `json:"authorityValue"`
 }
@@ -0,0 +1,184 @@
+package concordances
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+)
+
+const (
+	// defaultPageLimit is the size of a page requested with a cursor but without a limit
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	// streamPageSize is the number of concordances read from the driver at a time while streaming
+	streamPageSize = 500
+
+	cursorPrefix = "after:"
+
+	invalidLimitParameter  = "limit must be a positive integer no greater than 1000"
+	invalidCursorParameter = "cursor must be the nextCursor of a previous page"
+)
+
+// parsePage reads the limit and cursor parameters, paged is false when neither of them is present.
+// The Limit of the page is 0 when no limit is requested.
+func parsePage(m url.Values) (page Page, paged bool, apiErr *APIError) {
+	if m.Has("limit") {
+		limit, err := strconv.Atoi(m.Get("limit"))
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return Page{}, false, newBadRequestError(CodeInvalidParameter, "limit", invalidLimitParameter)
+		}
+		page.Limit = limit
+		paged = true
+	}
+	if m.Has("cursor") {
+		after, err := decodeCursor(m.Get("cursor"))
+		if err != nil {
+			return Page{}, false, newBadRequestError(CodeInvalidParameter, "cursor", invalidCursorParameter)
+		}
+		page.After = &after
+		paged = true
+	}
+	return page, paged, nil
+}
+
+// encodeCursor makes the opaque cursor of the page starting after the position
+func encodeCursor(after PagePosition) string {
+	encoded, _ := json.Marshal([]string{after.CanonicalUUID, after.Authority, after.AuthorityValue, after.Input})
+	return base64.RawURLEncoding.EncodeToString(append([]byte(cursorPrefix), encoded...))
+}
+
+func decodeCursor(cursor string) (PagePosition, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PagePosition{}, err
+	}
+	value, found := strings.CutPrefix(string(decoded), cursorPrefix)
+	if !found {
+		return PagePosition{}, errors.New("unknown cursor")
+	}
+	var fields []string
+	if err = json.Unmarshal([]byte(value), &fields); err != nil || len(fields) != 4 || fields[0] == "" {
+		return PagePosition{}, errors.New("invalid cursor position")
+	}
+	return PagePosition{CanonicalUUID: fields[0], Authority: fields[1], AuthorityValue: fields[2], Input: fields[3]}, nil
+}
+
+// positionOf is the position of the concordance in the order of pages
+func positionOf(c Concordance) PagePosition {
+	authority, known := AuthorityFromURI(c.Identifier.Authority)
+	if !known {
+		authority = c.Identifier.Authority
+	}
+	return PagePosition{
+		CanonicalUUID:  strings.TrimPrefix(c.Concept.ID, thingURL),
+		Authority:      authority,
+		AuthorityValue: c.Identifier.IdentifierValue,
+		Input:          c.Input,
+	}
+}
+
+// before tells whether p comes before other in the order of pages
+func (p PagePosition) before(other PagePosition) bool {
+	if p.CanonicalUUID != other.CanonicalUUID {
+		return p.CanonicalUUID < other.CanonicalUUID
+	}
+	if p.Authority != other.Authority {
+		return p.Authority < other.Authority
+	}
+	if p.AuthorityValue != other.AuthorityValue {
+		return p.AuthorityValue < other.AuthorityValue
+	}
+	return p.Input < other.Input
+}
+
+// readPage reads a page of concordances along with one more to find out whether another page follows,
+// in which case its cursor is returned as NextCursor
+func (hh *HTTPHandler) readPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+	concordances, found, err = hh.concordanceDriver.ReadPage(ctx, lookup, Page{After: page.After, Limit: page.Limit + 1})
+	if err != nil || !found {
+		return concordances, found, err
+	}
+	if len(concordances.Concordance) > page.Limit {
+		concordances.Concordance = concordances.Concordance[:page.Limit]
+		concordances.NextCursor = encodeCursor(positionOf(concordances.Concordance[page.Limit-1]))
+	}
+	return concordances, true, nil
+}
+
+// streamConcordances writes the concordances of the lookup in the streamed media type, reading them from the driver
+// one page at a time and flushing every page, so that no more than a page is ever held in memory. Every page is read
+// after the position of the last concordance written, though every page query still evaluates the whole lookup.
+// It starts after the position of the page and stops after the limit of the page if there is one.
+// Errors reading a page after the response has started can only be logged, the stream is then cut short.
+func (hh *HTTPHandler) streamConcordances(ctx context.Context, w http.ResponseWriter, logEntry *logger.LogEntry, tid string, mediaType string, lookup Lookup, page Page, strict bool, include includedFields) {
+	after, remaining := page.After, page.Limit
+	var writer concordanceWriter
+	var previous Concordance
+	flusher, canFlush := w.(http.Flusher)
+	for started := false; ; started = true {
+		size := streamPageSize
+		if page.Limit > 0 && remaining < size {
+			size = remaining
+		}
+
+		concordances, _, err := hh.concordanceDriver.ReadPage(ctx, lookup, Page{After: after, Limit: size})
+		if err != nil {
+			if !started {
+				writeLookupError(w, logEntry, tid, err)
+				return
+			}
+			logEntry.WithError(err).Error("Concordance stream cut short")
+			return
+		}
+
+		if !started {
+			if strict && len(concordances.Concordance) == 0 && page.After == nil {
+				writeError(w, logEntry, tid, &APIError{Status: http.StatusNotFound, Code: CodeNotFound, Message: noConcordancesFound})
+				return
+			}
+			w.Header().Set("Content-Type", contentType(mediaType))
+			w.Header().Set("Cache-Control", hh.cacheControlHeader)
+			w.WriteHeader(http.StatusOK)
+			writer = newConcordanceWriter(w, mediaType)
+		}
+
+		for _, concordance := range include.apply(concordances.Concordance) {
+			// pages are ordered by identifier, so the same identifier found for another input can only start the next page
+			if concordance == previous {
+				continue
+			}
+			if err = writer.write(concordance); err != nil {
+				break
+			}
+			previous = concordance
+		}
+		if err == nil {
+			err = writer.flush()
+		}
+		if err != nil {
+			logEntry.WithError(err).Error("Concordance stream cut short")
+			return
+		}
+		if canFlush {
+			flusher.Flush()
+		}
+
+		read := len(concordances.Concordance)
+		remaining -= read
+		if read < size || (page.Limit > 0 && remaining == 0) {
+			return
+		}
+		last := positionOf(concordances.Concordance[read-1])
+		after = &last
+	}
+}
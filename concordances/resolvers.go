@@ -0,0 +1,397 @@
+package concordances
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+)
+
+const (
+	// canonicalDeprecation returns whether the canonical concept is deprecated
+	canonicalDeprecation = `coalesce(canonical.isDeprecated, false) AS isDeprecated`
+	// inputTypeOfP returns whether the node p matched for the requested identifier is the preferred source of
+	// the canonical concept, a deprecated source or any other leaf node
+	inputTypeOfP = `CASE WHEN p.uuid = canonical.prefUUID THEN '` + InputTypeCanonical + `' WHEN p.isDeprecated THEN '` + InputTypeDeprecated + `' ELSE '` + InputTypeLeaf + `' END AS inputType`
+)
+
+// AuthorityResolver declares how the identifiers of an authority are read in both lookup directions.
+// Both queries return rows in the shape of neoReadStruct, with the requested conceptId or identifierValue as input.
+type AuthorityResolver struct {
+	// Authority is the name of the authority in the ontology systems, empty for the leaf node resolver
+	Authority string
+	// ConceptIDCypher returns the identifiers of the authority held by the concepts whose uuid is in $identifiers
+	ConceptIDCypher string
+	// AuthorityCypher returns the concepts holding an identifier of the authority whose value is in $authorityValue
+	AuthorityCypher string
+	// ValuesCypher returns as value, in order, the first $limit identifierValues of the authority greater than $after,
+	// only those AuthorityCypher finds a concept for. Authorities without it cannot be exported.
+	ValuesCypher string
+	// IdentifierPattern is the format the identifierValues of the authority are validated against,
+	// nil when it has no well defined one
+	IdentifierPattern *regexp.Regexp
+
+	// leafUUID, property, sourceLabel and canonicalLabel tell the drivers that cannot run Cypher where the queries
+	// find the identifiers: as uuid of the leaf nodes, or as property of the canonical nodes labelled canonicalLabel
+	// whose leaf nodes labelled sourceLabel are looked up by conceptId. The resolvers declaring neither are not found.
+	leafUUID       bool
+	property       string
+	sourceLabel    string
+	canonicalLabel string
+}
+
+// leafNodeResolver reads the authorities stored as authority and authorityValue of the leaf nodes of a concept,
+// it serves every authority without a resolver of its own, which are passed as $authorities and $authority
+var leafNodeResolver = AuthorityResolver{
+	ConceptIDCypher: `
+		MATCH (p:Thing)
+		WHERE p.uuid in $identifiers
+		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
+		MATCH (canonical)<-[:EQUIVALENT_TO]-(leafNode:Thing)
+		WHERE $authorities IS NULL OR leafNode.authority IN $authorities
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, ` + canonicalDeprecation + `, p.uuid as UUID, p.uuid as input, ` + inputTypeOfP + `, leafNode.authority as authority, leafNode.authorityValue as authorityValue`,
+	AuthorityCypher: `
+		MATCH (p:Thing)
+		WHERE p.authority = $authority AND p.authorityValue IN $authorityValue
+		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, ` + canonicalDeprecation + `, p.uuid as UUID, p.authorityValue as input, ` + inputTypeOfP + `, p.authority as authority, p.authorityValue as authorityValue`,
+	ValuesCypher: `
+		MATCH (p:Thing)-[:EQUIVALENT_TO]->(:Concept)
+		WHERE p.authority = $authority AND p.authorityValue > $after
+		RETURN DISTINCT p.authorityValue AS value
+		ORDER BY value
+		LIMIT $limit`,
+}
+
+// uppResolver reads the UPP identifiers, which are the uuids of the leaf nodes of a concept
+var uppResolver = AuthorityResolver{
+	Authority: "UPP",
+	ConceptIDCypher: `
+		MATCH (p:Thing)
+		WHERE p.uuid in $identifiers
+		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
+		MATCH (canonical)<-[:EQUIVALENT_TO]-(leafNode:Thing)
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, ` + canonicalDeprecation + `, p.uuid as UUID, p.uuid as input, ` + inputTypeOfP + `, 'UPP' as authority, leafNode.uuid as authorityValue`,
+	AuthorityCypher: `
+		MATCH (p:Thing)
+		WHERE p.uuid IN $authorityValue
+		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, ` + canonicalDeprecation + `, p.uuid as UUID, p.uuid as input, ` + inputTypeOfP + `, 'UPP' as authority, p.uuid as authorityValue`,
+	ValuesCypher: `
+		MATCH (p:Thing)-[:EQUIVALENT_TO]->(:Concept)
+		WHERE p.uuid > $after
+		RETURN DISTINCT p.uuid AS value
+		ORDER BY value
+		LIMIT $limit`,
+	IdentifierPattern: uuidPattern,
+	leafUUID:          true,
+}
+
+// PropertyResolver creates the resolver of an authority whose identifier is stored as property of the canonical node.
+// The concepts looked up by conceptId are matched by sourceLabel and the ones looked up by identifier by canonicalLabel.
+func PropertyResolver(authority string, property string, sourceLabel string, canonicalLabel string) AuthorityResolver {
+	return AuthorityResolver{
+		Authority: authority,
+		ConceptIDCypher: fmt.Sprintf(`
+		MATCH (p:%[3]s)
+		WHERE p.uuid in $identifiers
+		MATCH (p)-[:EQUIVALENT_TO]->(canonical:Concept)
+		WHERE exists(canonical.%[2]s)
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, %[4]s, p.uuid as UUID, p.uuid as input, %[5]s, '%[1]s' as authority, canonical.%[2]s as authorityValue`,
+			authority, property, sourceLabel, canonicalDeprecation, inputTypeOfP),
+		AuthorityCypher: fmt.Sprintf(`
+		MATCH (canonical:%[3]s)
+		WHERE canonical.%[2]s IN $authorityValue
+		AND exists(canonical.prefUUID)
+		RETURN DISTINCT canonical.prefUUID AS canonicalUUID, labels(canonical) AS types, canonical.prefLabel AS prefLabel, %[4]s, canonical.uuid as UUID, canonical.%[2]s as input, '%[5]s' AS inputType, '%[1]s' as authority, canonical.%[2]s as authorityValue`,
+			authority, property, canonicalLabel, canonicalDeprecation, InputTypeCanonical),
+		ValuesCypher: fmt.Sprintf(`
+		MATCH (canonical:%[2]s)
+		WHERE canonical.%[1]s > $after
+		AND exists(canonical.prefUUID)
+		RETURN DISTINCT canonical.%[1]s AS value
+		ORDER BY value
+		LIMIT $limit`,
+			property, canonicalLabel),
+		property:       property,
+		sourceLabel:    sourceLabel,
+		canonicalLabel: canonicalLabel,
+	}
+}
+
+// WithIdentifierPattern returns a copy of the resolver validating identifierValues against the pattern
+func (ar AuthorityResolver) WithIdentifierPattern(pattern string) AuthorityResolver {
+	ar.IdentifierPattern = regexp.MustCompile(pattern)
+	return ar
+}
+
+// ResolverRegistry holds the resolvers of the authorities needing a query of their own,
+// every other authority is read from the leaf nodes
+type ResolverRegistry struct {
+	resolvers []AuthorityResolver
+}
+
+// NewResolverRegistry creates a registry of the given resolvers, conceptId lookups union them in the given order
+func NewResolverRegistry(resolvers ...AuthorityResolver) *ResolverRegistry {
+	registry := &ResolverRegistry{}
+	for _, resolver := range resolvers {
+		registry.Register(resolver)
+	}
+	return registry
+}
+
+// Register adds the resolver of a new authority, replacing the one already registered for it
+func (rr *ResolverRegistry) Register(resolver AuthorityResolver) {
+	for i, registered := range rr.resolvers {
+		if registered.Authority == resolver.Authority {
+			rr.resolvers[i] = resolver
+			return
+		}
+	}
+	rr.resolvers = append(rr.resolvers, resolver)
+}
+
+// Resolver returns the resolver registered for the authority, or the leaf node one if there is none
+func (rr *ResolverRegistry) Resolver(authority string) AuthorityResolver {
+	if resolver, found := rr.registered(authority); found {
+		return resolver
+	}
+	return leafNodeResolver
+}
+
+func (rr *ResolverRegistry) registered(authority string) (AuthorityResolver, bool) {
+	for _, resolver := range rr.resolvers {
+		if resolver.Authority == authority {
+			return resolver, true
+		}
+	}
+	return AuthorityResolver{}, false
+}
+
+// conceptIDSelection is what a conceptId lookup restricted to some authorities reads: the leaf nodes of
+// leafAuthorities, or of every authority when it is nil, and the registered resolvers
+type conceptIDSelection struct {
+	leafNodes       bool
+	leafAuthorities []string
+	resolvers       []AuthorityResolver
+}
+
+// selectForConceptIDs selects what is read for the requested authority URIs, or for every authority if there are none.
+// It selects nothing if none of the requested authorities is known.
+func (rr *ResolverRegistry) selectForConceptIDs(authorityURIs []string) conceptIDSelection {
+	selection := conceptIDSelection{leafNodes: len(authorityURIs) == 0}
+	requested := map[string]bool{}
+	if len(authorityURIs) > 0 {
+		selection.leafAuthorities = []string{}
+		for _, uri := range authorityURIs {
+			authority, found := AuthorityFromURI(uri)
+			if !found {
+				continue
+			}
+			requested[authority] = true
+			selection.leafAuthorities = append(selection.leafAuthorities, authority)
+			if _, registered := rr.registered(authority); !registered {
+				selection.leafNodes = true
+			}
+		}
+	}
+
+	for _, resolver := range rr.resolvers {
+		if len(authorityURIs) == 0 || requested[resolver.Authority] {
+			selection.resolvers = append(selection.resolvers, resolver)
+		}
+	}
+	return selection
+}
+
+// conceptIDQuery unions the conceptId queries of the requested authority URIs, or of every authority if there are none.
+// It is nil if none of the requested authorities is known.
+func (rr *ResolverRegistry) conceptIDQuery(identifiers []string, authorityURIs []string) *cmneo4j.Query {
+	selection := rr.selectForConceptIDs(authorityURIs)
+	var branches []string
+	if selection.leafNodes {
+		branches = append(branches, leafNodeResolver.ConceptIDCypher)
+	}
+	for _, resolver := range selection.resolvers {
+		branches = append(branches, resolver.ConceptIDCypher)
+	}
+	if len(branches) == 0 {
+		return nil
+	}
+
+	params := map[string]interface{}{"identifiers": identifiers, "authorities": nil}
+	if selection.leafAuthorities != nil {
+		params["authorities"] = selection.leafAuthorities
+	}
+	return &cmneo4j.Query{
+		Cypher: strings.Join(branches, "\n\t\tUNION ALL\n"),
+		Params: params,
+	}
+}
+
+// authorityQuery is the query of the identifierValues of the authority, it is nil if the authority URI is unknown
+func (rr *ResolverRegistry) authorityQuery(authorityURI string, identifierValues []string) *cmneo4j.Query {
+	authority, found := AuthorityFromURI(authorityURI)
+	if !found {
+		return nil
+	}
+
+	return &cmneo4j.Query{
+		Cypher: rr.Resolver(authority).AuthorityCypher,
+		Params: map[string]interface{}{
+			"authorityValue": identifierValues,
+			"authority":      authority,
+		},
+	}
+}
+
+// translateQuery reads the identifiers of the to authority of the concepts holding the identifierValues of the from
+// authority, returning the identifierValue each one was found for as sourceValue. It is nil if either authority is unknown.
+func (rr *ResolverRegistry) translateQuery(fromURI string, toURI string, identifierValues []string) *cmneo4j.Query {
+	from, found := AuthorityFromURI(fromURI)
+	if !found {
+		return nil
+	}
+	to, found := AuthorityFromURI(toURI)
+	if !found {
+		return nil
+	}
+
+	toCypher := strings.ReplaceAll(rr.Resolver(to).ConceptIDCypher, "$identifiers", "[sourceUUID]")
+	return &cmneo4j.Query{
+		Cypher: fmt.Sprintf(`
+		CALL {%s
+		}
+		WITH canonicalUUID AS sourceUUID, authorityValue AS sourceValue
+		CALL {
+		WITH sourceUUID%s
+		}
+		RETURN DISTINCT sourceValue, canonicalUUID, types, prefLabel, isDeprecated, authority, authorityValue`,
+			rr.Resolver(from).AuthorityCypher, toCypher),
+		Params: map[string]interface{}{
+			"authorityValue": identifierValues,
+			"authority":      from,
+			"authorities":    []string{to},
+		},
+	}
+}
+
+// exportQuery reads every concept holding one of the first limit identifierValues of the authority greater than after,
+// running the AuthorityCypher of the authority on the values read by its ValuesCypher. The rows are ordered by
+// identifierValue so that the last one read is where the next page starts.
+// It is nil if the authority URI is unknown or its resolver has no ValuesCypher.
+func (rr *ResolverRegistry) exportQuery(authorityURI string, after string, limit int) *cmneo4j.Query {
+	authority, found := AuthorityFromURI(authorityURI)
+	if !found {
+		return nil
+	}
+	resolver := rr.Resolver(authority)
+	if resolver.ValuesCypher == "" {
+		return nil
+	}
+
+	authorityCypher := strings.ReplaceAll(resolver.AuthorityCypher, "$authorityValue", "values")
+	return &cmneo4j.Query{
+		Cypher: fmt.Sprintf(`
+		CALL {%s
+		}
+		WITH collect(value) AS values
+		CALL {
+		WITH values%s
+		}
+		RETURN canonicalUUID, types, prefLabel, isDeprecated, input, inputType, authority, authorityValue
+		ORDER BY authorityValue, canonicalUUID`,
+			resolver.ValuesCypher, authorityCypher),
+		Params: map[string]interface{}{
+			"authority": authority,
+			"after":     after,
+			"limit":     limit,
+		},
+	}
+}
+
+// groupParam matches the parameters of an AuthorityCypher, which are renamed for each group of a page query
+var groupParam = regexp.MustCompile(`\$(authority|authorityValue)\b`)
+
+// pageQuery reads a page of the concordances of the lookup, ordered so that consecutive pages never overlap.
+// The page starts after the position of the last row of the previous page rather than skipping the rows before it.
+// The authority queries of several groups are unioned, each one with parameters of its own.
+// Every page evaluates and sorts the whole lookup before keeping the rows after the cursor, so reading the n rows
+// of a lookup in pages of p costs about n²/2p rows, which the batch size bounds.
+// It is nil if none of the requested authorities is known.
+func (rr *ResolverRegistry) pageQuery(lookup Lookup, page Page) *cmneo4j.Query {
+	var union string
+	params := map[string]interface{}{}
+	if len(lookup.Groups) == 0 {
+		q := rr.conceptIDQuery(lookup.ConceptIDs, lookup.Authorities)
+		if q == nil {
+			return nil
+		}
+		union, params = q.Cypher, q.Params
+	} else {
+		var branches []string
+		for i, group := range lookup.Groups {
+			q := rr.authorityQuery(group.Authority, group.IdentifierValues)
+			if q == nil {
+				continue
+			}
+			suffix := strconv.Itoa(i)
+			branches = append(branches, groupParam.ReplaceAllString(q.Cypher, "$$${1}"+suffix))
+			for name, value := range q.Params {
+				params[name+suffix] = value
+			}
+		}
+		if len(branches) == 0 {
+			return nil
+		}
+		union = strings.Join(branches, "\n\t\tUNION ALL\n")
+	}
+
+	params["after"] = nil
+	if page.After != nil {
+		params["after"] = []string{page.After.CanonicalUUID, page.After.Authority, page.After.AuthorityValue, page.After.Input}
+	}
+	params["limit"] = page.Limit
+	return &cmneo4j.Query{
+		Cypher: fmt.Sprintf(`
+		CALL {%s
+		}
+		WITH DISTINCT canonicalUUID, types, prefLabel, isDeprecated, input, inputType, authority, authorityValue
+		WHERE $after IS NULL OR [canonicalUUID, authority, authorityValue, input] > $after
+		RETURN canonicalUUID, types, prefLabel, isDeprecated, input, inputType, authority, authorityValue
+		ORDER BY canonicalUUID, authority, authorityValue, input
+		LIMIT $limit`, union),
+		Params: params,
+	}
+}
+
+// CheckAuthorities fails when an authority of the default resolvers is not a system of the ontology, as its
+// identifiers could then never be looked up
+func CheckAuthorities() error {
+	var unknown []string
+	for _, resolver := range defaultResolvers.resolvers {
+		if _, found := AuthorityToURI(resolver.Authority); !found {
+			unknown = append(unknown, resolver.Authority)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("authorities %s are not systems of the ontology", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// defaultResolvers is the registry CypherDriver reads concordances with and requests are validated against.
+// Its authorities are systems of the ontology, the ones of financial instruments and their issuers (ISIN, FIGI and
+// TICKER) only identify canonical nodes.
+var defaultResolvers = NewResolverRegistry(
+	PropertyResolver("LEI", "leiCode", "Thing", "Concept").WithIdentifierPattern(`^[A-Z0-9]{20}$`),
+	PropertyResolver("ISO-3166-1", "iso31661", "Location", "Location").WithIdentifierPattern(`^[A-Z]{2}$`),
+	PropertyResolver("NAICS", "industryIdentifier", "NAICSIndustryClassification", "NAICSIndustryClassification").WithIdentifierPattern(`^[0-9]{2,6}$`),
+	PropertyResolver("FTAnI", "industryIdentifier", "FTAnIIndustryClassification", "FTAnIIndustryClassification"),
+	PropertyResolver("ISIN", "isin", "Thing", "Concept").WithIdentifierPattern(`^[A-Z]{2}[A-Z0-9]{9}[0-9]$`),
+	PropertyResolver("FIGI", "figiCode", "Thing", "Concept").WithIdentifierPattern(`^[B-DF-HJ-NP-TV-Z]{2}G[B-DF-HJ-NP-TV-Z0-9]{8}[0-9]$`),
+	PropertyResolver("TICKER", "ticker", "Thing", "Concept"),
+	uppResolver,
+)
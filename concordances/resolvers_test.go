@@ -0,0 +1,264 @@
+package concordances
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolversReadTheirAuthorityInBothDirections(t *testing.T) {
+	tests := []struct {
+		authority      string
+		conceptIDMatch string
+		conceptIDValue string
+		authorityMatch string
+		authorityValue string
+	}{
+		{
+			authority:      "LEI",
+			conceptIDMatch: "MATCH (p:Thing)",
+			conceptIDValue: "'LEI' as authority, canonical.leiCode as authorityValue",
+			authorityMatch: "MATCH (canonical:Concept)\n\t\tWHERE canonical.leiCode IN $authorityValue",
+			authorityValue: "'LEI' as authority, canonical.leiCode as authorityValue",
+		},
+		{
+			authority:      "ISO-3166-1",
+			conceptIDMatch: "MATCH (p:Location)",
+			conceptIDValue: "'ISO-3166-1' as authority, canonical.iso31661 as authorityValue",
+			authorityMatch: "MATCH (canonical:Location)\n\t\tWHERE canonical.iso31661 IN $authorityValue",
+			authorityValue: "'ISO-3166-1' as authority, canonical.iso31661 as authorityValue",
+		},
+		{
+			authority:      "NAICS",
+			conceptIDMatch: "MATCH (p:NAICSIndustryClassification)",
+			conceptIDValue: "'NAICS' as authority, canonical.industryIdentifier as authorityValue",
+			authorityMatch: "MATCH (canonical:NAICSIndustryClassification)\n\t\tWHERE canonical.industryIdentifier IN $authorityValue",
+			authorityValue: "'NAICS' as authority, canonical.industryIdentifier as authorityValue",
+		},
+		{
+			authority:      "FTAnI",
+			conceptIDMatch: "MATCH (p:FTAnIIndustryClassification)",
+			conceptIDValue: "'FTAnI' as authority, canonical.industryIdentifier as authorityValue",
+			authorityMatch: "MATCH (canonical:FTAnIIndustryClassification)\n\t\tWHERE canonical.industryIdentifier IN $authorityValue",
+			authorityValue: "'FTAnI' as authority, canonical.industryIdentifier as authorityValue",
+		},
+		{
+			authority:      "ISIN",
+			conceptIDMatch: "MATCH (p:Thing)",
+			conceptIDValue: "'ISIN' as authority, canonical.isin as authorityValue",
+			authorityMatch: "MATCH (canonical:Concept)\n\t\tWHERE canonical.isin IN $authorityValue",
+			authorityValue: "'ISIN' as authority, canonical.isin as authorityValue",
+		},
+		{
+			authority:      "FIGI",
+			conceptIDMatch: "MATCH (p:Thing)",
+			conceptIDValue: "'FIGI' as authority, canonical.figiCode as authorityValue",
+			authorityMatch: "MATCH (canonical:Concept)\n\t\tWHERE canonical.figiCode IN $authorityValue",
+			authorityValue: "'FIGI' as authority, canonical.figiCode as authorityValue",
+		},
+		{
+			authority:      "TICKER",
+			conceptIDMatch: "MATCH (p:Thing)",
+			conceptIDValue: "'TICKER' as authority, canonical.ticker as authorityValue",
+			authorityMatch: "MATCH (canonical:Concept)\n\t\tWHERE canonical.ticker IN $authorityValue",
+			authorityValue: "'TICKER' as authority, canonical.ticker as authorityValue",
+		},
+		{
+			authority:      "UPP",
+			conceptIDMatch: "MATCH (canonical)<-[:EQUIVALENT_TO]-(leafNode:Thing)",
+			conceptIDValue: "'UPP' as authority, leafNode.uuid as authorityValue",
+			authorityMatch: "WHERE p.uuid IN $authorityValue",
+			authorityValue: "'UPP' as authority, p.uuid as authorityValue",
+		},
+		{
+			authority:      "FACTSET",
+			conceptIDMatch: "WHERE $authorities IS NULL OR leafNode.authority IN $authorities",
+			conceptIDValue: "leafNode.authority as authority, leafNode.authorityValue as authorityValue",
+			authorityMatch: "WHERE p.authority = $authority AND p.authorityValue IN $authorityValue",
+			authorityValue: "p.authority as authority, p.authorityValue as authorityValue",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.authority, func(t *testing.T) {
+			resolver := defaultResolvers.Resolver(test.authority)
+			assert.Contains(t, resolver.ConceptIDCypher, "WHERE p.uuid in $identifiers")
+			assert.Contains(t, resolver.ConceptIDCypher, test.conceptIDMatch)
+			assert.Contains(t, resolver.ConceptIDCypher, test.conceptIDValue)
+			assert.Contains(t, resolver.AuthorityCypher, test.authorityMatch)
+			assert.Contains(t, resolver.AuthorityCypher, test.authorityValue)
+		})
+	}
+}
+
+func TestEveryRegisteredAuthorityIsASystemOfTheOntology(t *testing.T) {
+	for _, resolver := range defaultResolvers.resolvers {
+		uri, found := AuthorityToURI(resolver.Authority)
+		if assert.True(t, found, resolver.Authority) {
+			authority, found := AuthorityFromURI(uri)
+			assert.True(t, found, uri)
+			assert.Equal(t, resolver.Authority, authority)
+		}
+	}
+	assert.NoError(t, CheckAuthorities())
+}
+
+func TestRegisteringAPropertyResolver(t *testing.T) {
+	registry := NewResolverRegistry(PropertyResolver("LEI", "leiCode", "Thing", "Concept"))
+	registry.Register(PropertyResolver("ISO-3166-1", "iso31661", "Location", "Location"))
+
+	query := registry.authorityQuery("http://api.ft.com/system/ISO-3166-1", []string{"GB"})
+	assert.Contains(t, query.Cypher, "WHERE canonical.iso31661 IN $authorityValue")
+	assert.Equal(t, []string{"GB"}, query.Params["authorityValue"])
+
+	registry.Register(PropertyResolver("ISO-3166-1", "isoCode", "Location", "Location"))
+	query = registry.authorityQuery("http://api.ft.com/system/ISO-3166-1", []string{"GB"})
+	assert.Contains(t, query.Cypher, "WHERE canonical.isoCode IN $authorityValue", "registering again should replace the resolver")
+
+	assert.Nil(t, registry.authorityQuery("http://api.ft.com/system/UNKNOWN", []string{"GB"}))
+}
+
+func TestConceptIDQueryOnlyUnionsRequestedAuthorities(t *testing.T) {
+	cypherOf := func(authorities ...string) string {
+		var branches []string
+		for _, authority := range authorities {
+			branches = append(branches, defaultResolvers.Resolver(authority).ConceptIDCypher)
+		}
+		return strings.Join(branches, "\n\t\tUNION ALL\n")
+	}
+
+	tests := []struct {
+		name               string
+		authorities        []string
+		expectedCypher     string
+		expectedLeafFilter interface{}
+		expectedNoQuery    bool
+	}{
+		{
+			name:               "NoFilter",
+			expectedCypher:     cypherOf("", "LEI", "ISO-3166-1", "NAICS", "FTAnI", "ISIN", "FIGI", "TICKER", "UPP"),
+			expectedLeafFilter: nil,
+		},
+		{
+			name:               "LeafNodeAuthority",
+			authorities:        []string{"http://api.ft.com/system/FACTSET"},
+			expectedCypher:     cypherOf(""),
+			expectedLeafFilter: []string{"FACTSET"},
+		},
+		{
+			name:               "PropertyAuthority",
+			authorities:        []string{"http://api.ft.com/system/LEI"},
+			expectedCypher:     cypherOf("LEI"),
+			expectedLeafFilter: []string{"LEI"},
+		},
+		{
+			name:               "MixedAuthorities",
+			authorities:        []string{"http://api.ft.com/system/UPP", "http://api.ft.com/system/FT-TME"},
+			expectedCypher:     cypherOf("", "UPP"),
+			expectedLeafFilter: []string{"UPP", "TME"},
+		},
+		{
+			name:            "UnknownAuthority",
+			authorities:     []string{"http://api.ft.com/system/UNKNOWN"},
+			expectedNoQuery: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := defaultResolvers.conceptIDQuery([]string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, test.authorities)
+			if test.expectedNoQuery {
+				assert.Nil(t, query)
+				return
+			}
+			assert.Equal(t, test.expectedCypher, query.Cypher)
+			assert.Equal(t, test.expectedLeafFilter, query.Params["authorities"])
+		})
+	}
+}
+
+func TestTranslateQueryChainsTheResolversOfBothAuthorities(t *testing.T) {
+	query := defaultResolvers.translateQuery("http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI", []string{"7IV872-E"})
+
+	assert.Contains(t, query.Cypher, leafNodeResolver.AuthorityCypher)
+	assert.Contains(t, query.Cypher, "MATCH (p:Thing)\n\t\tWHERE p.uuid in [sourceUUID]")
+	assert.Contains(t, query.Cypher, "'LEI' as authority, canonical.leiCode as authorityValue")
+	assert.NotContains(t, query.Cypher, "$identifiers")
+	assert.Contains(t, query.Cypher, "RETURN DISTINCT sourceValue, canonicalUUID, types, prefLabel, isDeprecated, authority, authorityValue")
+	assert.Equal(t, map[string]interface{}{
+		"authorityValue": []string{"7IV872-E"},
+		"authority":      "FACTSET",
+		"authorities":    []string{"LEI"},
+	}, query.Params)
+
+	assert.Nil(t, defaultResolvers.translateQuery("http://api.ft.com/system/UNKNOWN", "http://api.ft.com/system/LEI", []string{"7IV872-E"}))
+	assert.Nil(t, defaultResolvers.translateQuery("http://api.ft.com/system/FACTSET", "http://api.ft.com/system/UNKNOWN", []string{"7IV872-E"}))
+}
+
+func TestPageQueryOrdersAndWindowsTheLookup(t *testing.T) {
+	after := PagePosition{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Authority: "FACTSET", AuthorityValue: "7IV872-E", Input: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}
+	query := defaultResolvers.pageQuery(Lookup{ConceptIDs: []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}}, Page{After: &after, Limit: 10})
+
+	assert.Contains(t, query.Cypher, leafNodeResolver.ConceptIDCypher)
+	assert.Contains(t, query.Cypher, "WHERE $after IS NULL OR [canonicalUUID, authority, authorityValue, input] > $after")
+	assert.Contains(t, query.Cypher, "ORDER BY canonicalUUID, authority, authorityValue, input")
+	assert.NotContains(t, query.Cypher, "SKIP")
+	assert.Equal(t, []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "FACTSET", "7IV872-E", "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, query.Params["after"])
+	assert.Equal(t, 10, query.Params["limit"])
+	assert.Nil(t, defaultResolvers.pageQuery(Lookup{ConceptIDs: []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}}, Page{Limit: 10}).Params["after"])
+	assert.Equal(t, []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, query.Params["identifiers"])
+
+	assert.Nil(t, defaultResolvers.pageQuery(Lookup{ConceptIDs: []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, Authorities: []string{"http://api.ft.com/system/UNKNOWN"}}, Page{Limit: 10}))
+}
+
+func TestPageQueryGivesEveryAuthorityGroupParametersOfItsOwn(t *testing.T) {
+	query := defaultResolvers.pageQuery(Lookup{Groups: []AuthorityIdentifiers{
+		{Authority: "http://api.ft.com/system/FACTSET", IdentifierValues: []string{"7IV872-E"}},
+		{Authority: "http://api.ft.com/system/UNKNOWN", IdentifierValues: []string{"ABC"}},
+		{Authority: "http://api.ft.com/system/LEI", IdentifierValues: []string{"VNF516RB4DFV5NQ22UF0"}},
+	}}, Page{Limit: 10})
+
+	assert.Contains(t, query.Cypher, "$authorityValue0")
+	assert.Contains(t, query.Cypher, "$authority0")
+	assert.Contains(t, query.Cypher, "$authorityValue2")
+	assert.NotContains(t, query.Cypher, "$authorityValue ")
+	assert.NotContains(t, query.Cypher, "$authority ")
+	assert.Equal(t, map[string]interface{}{
+		"authorityValue0": []string{"7IV872-E"},
+		"authority0":      "FACTSET",
+		"authorityValue2": []string{"VNF516RB4DFV5NQ22UF0"},
+		"authority2":      "LEI",
+		"after":           nil,
+		"limit":           10,
+	}, query.Params)
+
+	assert.Nil(t, defaultResolvers.pageQuery(Lookup{Groups: []AuthorityIdentifiers{{Authority: "http://api.ft.com/system/UNKNOWN", IdentifierValues: []string{"ABC"}}}}, Page{Limit: 10}))
+}
+
+func TestExportQueryResolvesTheValuesOfTheAuthority(t *testing.T) {
+	tests := []struct {
+		authority string
+		resolver  AuthorityResolver
+	}{
+		{authority: "http://api.ft.com/system/FACTSET", resolver: leafNodeResolver},
+		{authority: "http://api.ft.com/system/UPP", resolver: uppResolver},
+		{authority: "http://api.ft.com/system/LEI", resolver: defaultResolvers.Resolver("LEI")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.authority, func(t *testing.T) {
+			query := defaultResolvers.exportQuery(test.authority, "7IV872-E", 1000)
+			assert.Contains(t, query.Cypher, test.resolver.ValuesCypher)
+			assert.Contains(t, query.Cypher, strings.ReplaceAll(test.resolver.AuthorityCypher, "$authorityValue", "values"))
+			assert.Contains(t, query.Cypher, "ORDER BY authorityValue, canonicalUUID")
+			assert.Equal(t, "7IV872-E", query.Params["after"])
+			assert.Equal(t, 1000, query.Params["limit"])
+		})
+	}
+
+	assert.Nil(t, defaultResolvers.exportQuery("http://api.ft.com/system/UNKNOWN", "", 1000))
+
+	registry := NewResolverRegistry(AuthorityResolver{Authority: "LEI", ConceptIDCypher: "RETURN 1", AuthorityCypher: "RETURN 1"})
+	assert.Nil(t, registry.exportQuery("http://api.ft.com/system/LEI", "", 1000), "resolvers without ValuesCypher cannot be exported")
+}
@@ -0,0 +1,139 @@
+package concordances
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format written by SnapshotWriter
+const SnapshotVersion = 1
+
+// A snapshot is a gzip compressed stream of JSON lines: a SnapshotHeader, a SnapshotRecord per identifier
+// of a canonical concept and a trailer holding the number of records and the SHA-256 checksum of their lines.
+
+// SnapshotHeader is the first line of a snapshot
+type SnapshotHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Source is where the concordances were exported from
+	Source string `json:"source,omitempty"`
+}
+
+// SnapshotRecord is an identifier of a canonical concept
+type SnapshotRecord struct {
+	CanonicalUUID  string   `json:"canonicalUUID"`
+	Types          []string `json:"types"`
+	PrefLabel      string   `json:"prefLabel,omitempty"`
+	IsDeprecated   bool     `json:"isDeprecated,omitempty"`
+	Authority      string   `json:"authority"`
+	AuthorityValue string   `json:"authorityValue"`
+	// SourceTypes are the labels of the source concept of a UPP identifier, which restrict the identifiers stored on
+	// the canonical concept that a conceptId lookup of the source concept finds
+	SourceTypes []string `json:"sourceTypes,omitempty"`
+}
+
+// snapshotTrailer is the last line of a snapshot
+type snapshotTrailer struct {
+	Records  int    `json:"records"`
+	Checksum string `json:"sha256"`
+}
+
+// snapshotLine is any line following the header, it is the trailer when it has a checksum
+type snapshotLine struct {
+	SnapshotRecord
+	snapshotTrailer
+}
+
+// SnapshotWriter writes a snapshot one record at a time
+type SnapshotWriter struct {
+	gz       *gzip.Writer
+	checksum hash.Hash
+	records  int
+}
+
+// NewSnapshotWriter starts a snapshot of the current version with the header
+func NewSnapshotWriter(w io.Writer, header SnapshotHeader) (*SnapshotWriter, error) {
+	header.Version = SnapshotVersion
+	sw := &SnapshotWriter{gz: gzip.NewWriter(w), checksum: sha256.New()}
+	if err := json.NewEncoder(sw.gz).Encode(header); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (sw *SnapshotWriter) Write(record SnapshotRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	sw.checksum.Write(line)
+	sw.records++
+	_, err = sw.gz.Write(line)
+	return err
+}
+
+// Close ends the snapshot with its trailer, it does not close the underlying writer
+func (sw *SnapshotWriter) Close() error {
+	trailer := snapshotTrailer{Records: sw.records, Checksum: hex.EncodeToString(sw.checksum.Sum(nil))}
+	if err := json.NewEncoder(sw.gz).Encode(trailer); err != nil {
+		return err
+	}
+	return sw.gz.Close()
+}
+
+var errTruncatedSnapshot = errors.New("snapshot is truncated")
+
+// ReadSnapshot reads a whole snapshot, failing unless its version is known and its records match the trailer
+func ReadSnapshot(r io.Reader) (header SnapshotHeader, records []SnapshotRecord, err error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return SnapshotHeader{}, nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	lines := bufio.NewReader(gz)
+	line, err := lines.ReadBytes('\n')
+	if err != nil {
+		return SnapshotHeader{}, nil, errTruncatedSnapshot
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return SnapshotHeader{}, nil, fmt.Errorf("decoding snapshot header: %w", err)
+	}
+	if header.Version != SnapshotVersion {
+		return SnapshotHeader{}, nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+
+	checksum := sha256.New()
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err != nil {
+			return SnapshotHeader{}, nil, errTruncatedSnapshot
+		}
+		var decoded snapshotLine
+		if err := json.Unmarshal(line, &decoded); err != nil {
+			return SnapshotHeader{}, nil, fmt.Errorf("decoding snapshot record %d: %w", len(records)+1, err)
+		}
+
+		if trailer := decoded.snapshotTrailer; trailer.Checksum != "" {
+			if trailer.Records != len(records) || trailer.Checksum != hex.EncodeToString(checksum.Sum(nil)) {
+				return SnapshotHeader{}, nil, errors.New("snapshot checksum mismatch")
+			}
+			if rest, _ := io.ReadAll(lines); len(bytes.TrimSpace(rest)) > 0 {
+				return SnapshotHeader{}, nil, errors.New("snapshot continues after its trailer")
+			}
+			return header, records, nil
+		}
+		checksum.Write(line)
+		records = append(records, decoded.SnapshotRecord)
+	}
+}
@@ -0,0 +1,191 @@
+package concordances
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SnapshotDiff is how the concordances of a target differ from the ones of a base, per authority
+type SnapshotDiff struct {
+	Base        string          `json:"base"`
+	Target      string          `json:"target"`
+	Authorities []AuthorityDiff `json:"authorities"`
+}
+
+// AuthorityDiff holds the identifiers of an authority only found in the target, only found in the base, and the ones
+// concorded to other concepts in the target than in the base
+type AuthorityDiff struct {
+	Authority string        `json:"authority"`
+	Added     []Concordance `json:"added"`
+	Removed   []Concordance `json:"removed"`
+	Repointed []Repointing  `json:"repointed"`
+}
+
+// Repointing is an identifier concorded to the From concepts in the base and to the To concepts in the target
+type Repointing struct {
+	Identifier Identifier `json:"identifier"`
+	From       []Concept  `json:"from"`
+	To         []Concept  `json:"to"`
+}
+
+// CollectSnapshot reads every page of the source, pageSize canonical concepts at a time
+func CollectSnapshot(ctx context.Context, source SnapshotSource, pageSize int) ([]SnapshotRecord, error) {
+	var records []SnapshotRecord
+	after := ""
+	for {
+		page, next, err := source.SnapshotPage(ctx, after, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("reading canonical concepts after %q: %w", after, err)
+		}
+		records = append(records, page...)
+		if next == "" {
+			return records, nil
+		}
+		after = next
+	}
+}
+
+// DiffSnapshots compares the identifiers of the target records with the ones of the base records. Authorities
+// unknown to the API are reported by name rather than by URI. The authorities without any difference are left out.
+func DiffSnapshots(base []SnapshotRecord, target []SnapshotRecord, publicAPIURL string) ([]AuthorityDiff, error) {
+	baseConcepts, err := conceptsByIdentifier(base, publicAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	targetConcepts, err := conceptsByIdentifier(target, publicAPIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	byAuthority := map[string]*AuthorityDiff{}
+	diffOf := func(authority string) *AuthorityDiff {
+		if _, found := byAuthority[authority]; !found {
+			byAuthority[authority] = &AuthorityDiff{Authority: authority, Added: []Concordance{}, Removed: []Concordance{}, Repointed: []Repointing{}}
+		}
+		return byAuthority[authority]
+	}
+
+	for identifier, concepts := range baseConcepts {
+		targets, found := targetConcepts[identifier]
+		switch {
+		case !found:
+			diff := diffOf(identifier.Authority)
+			for _, concept := range concepts {
+				diff.Removed = append(diff.Removed, Concordance{Concept: concept, Identifier: identifier})
+			}
+		case !sameConcepts(concepts, targets):
+			diff := diffOf(identifier.Authority)
+			diff.Repointed = append(diff.Repointed, Repointing{Identifier: identifier, From: concepts, To: targets})
+		}
+	}
+	for identifier, concepts := range targetConcepts {
+		if _, found := baseConcepts[identifier]; !found {
+			diff := diffOf(identifier.Authority)
+			for _, concept := range concepts {
+				diff.Added = append(diff.Added, Concordance{Concept: concept, Identifier: identifier})
+			}
+		}
+	}
+
+	diffs := []AuthorityDiff{}
+	for _, diff := range byAuthority {
+		sortConcordancesByIdentifier(diff.Added)
+		sortConcordancesByIdentifier(diff.Removed)
+		sort.Slice(diff.Repointed, func(i, j int) bool {
+			return diff.Repointed[i].Identifier.IdentifierValue < diff.Repointed[j].Identifier.IdentifierValue
+		})
+		diffs = append(diffs, *diff)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Authority < diffs[j].Authority })
+	return diffs, nil
+}
+
+// conceptsByIdentifier holds the concepts of every identifier of the records, in the order of their ID
+func conceptsByIdentifier(records []SnapshotRecord, publicAPIURL string) (map[Identifier][]Concept, error) {
+	concepts := map[Identifier][]Concept{}
+	for _, record := range records {
+		concept, err := neoConcept(snapshotRow(record, "", ""), publicAPIURL)
+		if err != nil {
+			return nil, err
+		}
+		authority, found := AuthorityToURI(record.Authority)
+		if !found {
+			authority = record.Authority
+		}
+		identifier := Identifier{Authority: authority, IdentifierValue: record.AuthorityValue}
+		if !containsConcept(concepts[identifier], concept.ID) {
+			concepts[identifier] = append(concepts[identifier], concept)
+		}
+	}
+	for _, c := range concepts {
+		sort.Slice(c, func(i, j int) bool { return c[i].ID < c[j].ID })
+	}
+	return concepts, nil
+}
+
+func containsConcept(concepts []Concept, id string) bool {
+	for _, c := range concepts {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// sameConcepts tells whether both lists, in the order of their ID, hold the same concept IDs
+func sameConcepts(a []Concept, b []Concept) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func sortConcordancesByIdentifier(concordances []Concordance) {
+	sort.Slice(concordances, func(i, j int) bool {
+		a, b := concordances[i], concordances[j]
+		if a.Identifier.IdentifierValue != b.Identifier.IdentifierValue {
+			return a.Identifier.IdentifierValue < b.Identifier.IdentifierValue
+		}
+		return a.Concept.ID < b.Concept.ID
+	})
+}
+
+// WriteSnapshotDiff writes the diff for people to read: a summary of every authority followed by its identifiers
+// added (+), removed (-) and re-pointed (~)
+func WriteSnapshotDiff(w io.Writer, diff SnapshotDiff) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", diff.Base, diff.Target)
+	if len(diff.Authorities) == 0 {
+		b.WriteString("No differences\n")
+	}
+	for _, a := range diff.Authorities {
+		fmt.Fprintf(&b, "\n%s: %d added, %d removed, %d re-pointed\n", a.Authority, len(a.Added), len(a.Removed), len(a.Repointed))
+		for _, c := range a.Added {
+			fmt.Fprintf(&b, "+ %s -> %s\n", c.Identifier.IdentifierValue, describeConcepts(c.Concept))
+		}
+		for _, c := range a.Removed {
+			fmt.Fprintf(&b, "- %s -> %s\n", c.Identifier.IdentifierValue, describeConcepts(c.Concept))
+		}
+		for _, r := range a.Repointed {
+			fmt.Fprintf(&b, "~ %s -> %s, was %s\n", r.Identifier.IdentifierValue, describeConcepts(r.To...), describeConcepts(r.From...))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func describeConcepts(concepts ...Concept) string {
+	described := make([]string, 0, len(concepts))
+	for _, c := range concepts {
+		described = append(described, fmt.Sprintf("%s (%s)", c.ID, c.PrefLabel))
+	}
+	return strings.Join(described, ", ")
+}
@@ -0,0 +1,109 @@
+package concordances
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	target := []SnapshotRecord{}
+	for _, record := range snapshotRecords {
+		switch record.Authority {
+		case "LEI":
+			continue
+		case "FACTSET":
+			record.CanonicalUUID, record.PrefLabel, record.Types = "5aba454b-3e31-31b9-bdeb-0caf83f62b44", "Romania", []string{"Thing", "Concept", "Location"}
+		}
+		target = append(target, record)
+	}
+	target = append(target,
+		SnapshotRecord{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "TME", AuthorityValue: "QmFuayBvZiBUZXN0-T04="},
+		SnapshotRecord{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "UNKNOWN", AuthorityValue: "42"},
+	)
+
+	diffs, err := DiffSnapshots(snapshotRecords, target, "http://api.ft.com")
+	assert.NoError(t, err)
+
+	var authorities []string
+	for _, diff := range diffs {
+		authorities = append(authorities, diff.Authority)
+	}
+	assert.Equal(t, []string{
+		"UNKNOWN",
+		"http://api.ft.com/system/FACTSET",
+		"http://api.ft.com/system/FT-TME",
+		"http://api.ft.com/system/LEI",
+	}, authorities)
+
+	assert.Equal(t, []string{bankOfTestID + " UNKNOWN 42"}, identifiers(diffs[0].Added))
+
+	assert.Empty(t, diffs[1].Added)
+	assert.Empty(t, diffs[1].Removed)
+	if assert.Len(t, diffs[1].Repointed, 1) {
+		repointed := diffs[1].Repointed[0]
+		assert.Equal(t, Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}, repointed.Identifier)
+		assert.Equal(t, bankOfTestID, repointed.From[0].ID)
+		assert.Equal(t, romaniaID, repointed.To[0].ID)
+	}
+
+	assert.Equal(t, []string{bankOfTestID + " http://api.ft.com/system/FT-TME QmFuayBvZiBUZXN0-T04="}, identifiers(diffs[2].Added))
+	assert.Equal(t, []string{bankOfTestID + " http://api.ft.com/system/LEI VNF516RB4DFV5NQ22UF0"}, identifiers(diffs[3].Removed))
+}
+
+func TestDiffSnapshotsWithoutDifferences(t *testing.T) {
+	diffs, err := DiffSnapshots(snapshotRecords, snapshotRecords, "http://api.ft.com")
+	assert.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestWriteSnapshotDiff(t *testing.T) {
+	bankOfTest := Concept{ID: bankOfTestID, PrefLabel: "Bank of Test"}
+	romania := Concept{ID: romaniaID, PrefLabel: "Romania"}
+	diff := SnapshotDiff{
+		Base:   "prod.snapshot.gz",
+		Target: "bolt://staging:7687",
+		Authorities: []AuthorityDiff{
+			{
+				Authority: "http://api.ft.com/system/FACTSET",
+				Added:     []Concordance{{Concept: bankOfTest, Identifier: Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"}}},
+				Removed:   []Concordance{{Concept: romania, Identifier: Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "000001-E"}}},
+				Repointed: []Repointing{{Identifier: Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "000002-E"}, From: []Concept{romania}, To: []Concept{bankOfTest}}},
+			},
+		},
+	}
+
+	var out strings.Builder
+	assert.NoError(t, WriteSnapshotDiff(&out, diff))
+	assert.Equal(t, `--- prod.snapshot.gz
++++ bolt://staging:7687
+
+http://api.ft.com/system/FACTSET: 1 added, 1 removed, 1 re-pointed
++ 7IV872-E -> `+bankOfTestID+` (Bank of Test)
+- 000001-E -> `+romaniaID+` (Romania)
+~ 000002-E -> `+bankOfTestID+` (Bank of Test), was `+romaniaID+` (Romania)
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, WriteSnapshotDiff(&out, SnapshotDiff{Base: "a", Target: "b"}))
+	assert.Equal(t, "--- a\n+++ b\nNo differences\n", out.String())
+}
+
+func TestCollectSnapshot(t *testing.T) {
+	driver := loadFixtures(t)
+
+	paged, err := CollectSnapshot(context.Background(), driver, 2)
+	assert.NoError(t, err)
+	whole, err := CollectSnapshot(context.Background(), driver, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, whole, paged)
+	assert.Contains(t, paged, SnapshotRecord{
+		CanonicalUUID:  "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		Types:          []string{"Thing", "Concept", "Organisation"},
+		PrefLabel:      "Bank of Test",
+		Authority:      "FACTSET",
+		AuthorityValue: "7IV872-E",
+	})
+}
@@ -0,0 +1,405 @@
+package concordances
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	logger "github.com/Financial-Times/go-logger/v2"
+)
+
+// SnapshotDriver answers lookups from the concordance snapshot of a file, reloading it whenever the file changes.
+// A snapshot does not hold source concepts, so a requested identifier other than a property of the canonical
+// concept or its own UUID is always a leaf: deprecated source concepts are not told apart.
+type SnapshotDriver struct {
+	path         string
+	publicAPIURL string
+	resolvers    *ResolverRegistry
+	index        atomic.Pointer[snapshotIndex]
+
+	mu sync.Mutex
+	// loadErr is why the snapshot file could not be loaded the last time it was, nil if it was
+	loadErr error
+}
+
+// snapshotIndex is a loaded snapshot, it is never modified once loaded
+type snapshotIndex struct {
+	header  SnapshotHeader
+	modTime time.Time
+	size    int64
+	// concepts are the records of each canonical UUID
+	concepts map[string][]SnapshotRecord
+	// canonicals are the canonical UUIDs holding each identifierValue of each authority, in order
+	canonicals map[string]map[string][]string
+	// values are the identifierValues of each authority, in order
+	values map[string][]string
+	// sourceTypes are the labels of each source concept, for the snapshots recording them
+	sourceTypes map[string][]string
+}
+
+// NewSnapshotDriver loads the snapshot of the file
+func NewSnapshotDriver(publicAPIURL string, path string) (*SnapshotDriver, error) {
+	_, err := url.ParseRequestURI(publicAPIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sd := &SnapshotDriver{path: path, publicAPIURL: publicAPIURL, resolvers: defaultResolvers}
+	if err := sd.Reload(); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// Reload loads the snapshot file again, the snapshot served so far is kept when it cannot be loaded
+func (sd *SnapshotDriver) Reload() error {
+	err := sd.load()
+	sd.setLoadErr(err)
+	return err
+}
+
+func (sd *SnapshotDriver) load() error {
+	f, err := os.Open(sd.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, records, err := ReadSnapshot(f)
+	if err != nil {
+		return fmt.Errorf("loading snapshot %s: %w", sd.path, err)
+	}
+	sd.index.Store(newSnapshotIndex(header, records, info))
+	return nil
+}
+
+func newSnapshotIndex(header SnapshotHeader, records []SnapshotRecord, info os.FileInfo) *snapshotIndex {
+	idx := &snapshotIndex{
+		header:      header,
+		modTime:     info.ModTime(),
+		size:        info.Size(),
+		concepts:    map[string][]SnapshotRecord{},
+		canonicals:  map[string]map[string][]string{},
+		values:      map[string][]string{},
+		sourceTypes: map[string][]string{},
+	}
+	for _, record := range records {
+		if record.Authority == uppResolver.Authority && len(record.SourceTypes) > 0 {
+			idx.sourceTypes[record.AuthorityValue] = record.SourceTypes
+		}
+		idx.concepts[record.CanonicalUUID] = append(idx.concepts[record.CanonicalUUID], record)
+		byValue, found := idx.canonicals[record.Authority]
+		if !found {
+			byValue = map[string][]string{}
+			idx.canonicals[record.Authority] = byValue
+		}
+		if len(byValue[record.AuthorityValue]) == 0 {
+			idx.values[record.Authority] = append(idx.values[record.Authority], record.AuthorityValue)
+		}
+		if !containsString(byValue[record.AuthorityValue], record.CanonicalUUID) {
+			byValue[record.AuthorityValue] = append(byValue[record.AuthorityValue], record.CanonicalUUID)
+		}
+	}
+	for authority, byValue := range idx.canonicals {
+		for _, canonicals := range byValue {
+			sort.Strings(canonicals)
+		}
+		sort.Strings(idx.values[authority])
+	}
+	return idx
+}
+
+// Watch reloads the snapshot whenever the modification time or the size of its file changes, checking the file
+// every interval until ctx is done
+func (sd *SnapshotDriver) Watch(ctx context.Context, interval time.Duration, log *logger.UPPLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(sd.path)
+		if err != nil {
+			sd.setLoadErr(err)
+			log.WithError(err).Warn("Checking the concordance snapshot file")
+			continue
+		}
+		loaded := sd.index.Load()
+		if info.ModTime().Equal(loaded.modTime) && info.Size() == loaded.size {
+			continue
+		}
+		if err := sd.Reload(); err != nil {
+			log.WithError(err).Error("Reloading the concordance snapshot, the previous one is still served")
+			continue
+		}
+		log.WithField("createdAt", sd.CreatedAt()).Info("Reloaded the concordance snapshot")
+	}
+}
+
+// CreatedAt is when the snapshot served was created
+func (sd *SnapshotDriver) CreatedAt() time.Time {
+	return sd.index.Load().header.CreatedAt
+}
+
+// AgeCheck is a healthcheck failing once the snapshot served is older than maxAge
+func (sd *SnapshotDriver) AgeCheck(maxAge time.Duration) fthealth.Check {
+	return fthealth.Check{
+		BusinessImpact:   "Concordances served may be out of date",
+		Name:             "Check the age of the concordance snapshot",
+		PanicGuide:       "https://runbooks.ftops.tech/public-concordances-api",
+		Severity:         2,
+		TechnicalSummary: fmt.Sprintf("The concordance snapshot served was created more than %s ago, a newer one must be exported to %s", maxAge, sd.path),
+		Checker: func() (string, error) {
+			age := time.Since(sd.CreatedAt()).Round(time.Second)
+			if age > maxAge {
+				return "", fmt.Errorf("snapshot created at %s is %s old", sd.CreatedAt().Format(time.RFC3339), age)
+			}
+			return fmt.Sprintf("Snapshot created at %s is %s old", sd.CreatedAt().Format(time.RFC3339), age), nil
+		},
+	}
+}
+
+// ValidityCheck is a healthcheck failing while the snapshot file cannot be loaded, the previous snapshot being served
+func (sd *SnapshotDriver) ValidityCheck() fthealth.Check {
+	return fthealth.Check{
+		BusinessImpact:   "Concordances served may be out of date",
+		Name:             "Check the concordance snapshot file can be loaded",
+		PanicGuide:       "https://runbooks.ftops.tech/public-concordances-api",
+		Severity:         2,
+		TechnicalSummary: fmt.Sprintf("The concordance snapshot file %s is missing or invalid, the snapshot loaded before it is still served until a valid one is exported", sd.path),
+		Checker: func() (string, error) {
+			sd.mu.Lock()
+			defer sd.mu.Unlock()
+			if sd.loadErr != nil {
+				return "", fmt.Errorf("serving the snapshot created at %s: %w", sd.CreatedAt().Format(time.RFC3339), sd.loadErr)
+			}
+			return fmt.Sprintf("Snapshot file %s is loaded", sd.path), nil
+		},
+	}
+}
+
+func (sd *SnapshotDriver) setLoadErr(err error) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.loadErr = err
+}
+
+// CheckConnectivity always succeeds as a snapshot is loaded from the start
+func (sd *SnapshotDriver) CheckConnectivity(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// ReadByConceptID reads the identifiers of the concepts, restricted to the given authority URIs unless there are none
+func (sd *SnapshotDriver) ReadByConceptID(ctx context.Context, identifiers []string, authorities []string) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+	return concordancesOfRows(sd.index.Load().conceptIDRows(sd.resolvers, identifiers, authorities), sd.publicAPIURL)
+}
+
+func (sd *SnapshotDriver) ReadByAuthority(ctx context.Context, authority string, identifierValues []string) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+	name, known := AuthorityFromURI(authority)
+	if !known {
+		return Concordances{}, false, nil
+	}
+	return concordancesOfRows(sd.index.Load().authorityRows(sd.resolvers, name, identifierValues), sd.publicAPIURL)
+}
+
+// ReadByAuthorities reads the identifierValues of every group and merges their results
+func (sd *SnapshotDriver) ReadByAuthorities(ctx context.Context, groups []AuthorityIdentifiers) (concordances Concordances, found bool, err error) {
+	var read [][]Concordance
+	for _, group := range groups {
+		groupConcordances, groupFound, err := sd.ReadByAuthority(ctx, group.Authority, group.IdentifierValues)
+		if err != nil {
+			return Concordances{}, false, err
+		}
+		if groupFound {
+			read = append(read, groupConcordances.Concordance)
+		}
+	}
+
+	if len(read) == 0 {
+		return Concordances{}, false, nil
+	}
+	return Concordances{Concordance: mergeConcordances(read...)}, true, nil
+}
+
+// Translate reads the identifiers of the to authority of the concepts identified by the identifierValues of the from
+// authority
+func (sd *SnapshotDriver) Translate(ctx context.Context, from string, to string, identifierValues []string) (translations []Translation, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return []Translation{}, false, err
+	}
+	fromName, fromKnown := AuthorityFromURI(from)
+	toName, toKnown := AuthorityFromURI(to)
+	if !fromKnown || !toKnown {
+		return []Translation{}, false, nil
+	}
+
+	idx := sd.index.Load()
+	translations = []Translation{}
+	seen := map[string]bool{}
+	for _, value := range identifierValues {
+		for _, canonical := range idx.canonicals[fromName][value] {
+			for _, record := range idx.concepts[canonical] {
+				key := value + "\x00" + canonical + "\x00" + record.AuthorityValue
+				if record.Authority != toName || seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				row := snapshotRow(record, "", "")
+				concept, err := neoConcept(row, sd.publicAPIURL)
+				if err != nil {
+					return []Translation{}, false, fmt.Errorf("transforming result from datastore: %w", err)
+				}
+				translations = append(translations, Translation{
+					From:    Identifier{Authority: from, IdentifierValue: value},
+					To:      Identifier{Authority: to, IdentifierValue: record.AuthorityValue},
+					Concept: concept,
+				})
+			}
+		}
+	}
+	return translations, len(translations) > 0, nil
+}
+
+// ReadPage reads a single page of the concordances of the lookup, in the order of canonical concept and identifier
+func (sd *SnapshotDriver) ReadPage(ctx context.Context, lookup Lookup, page Page) (concordances Concordances, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, false, err
+	}
+
+	idx := sd.index.Load()
+	var rows []neoReadStruct
+	if len(lookup.Groups) == 0 {
+		rows = idx.conceptIDRows(sd.resolvers, lookup.ConceptIDs, lookup.Authorities)
+	} else {
+		for _, group := range lookup.Groups {
+			if name, known := AuthorityFromURI(group.Authority); known {
+				rows = append(rows, idx.authorityRows(sd.resolvers, name, group.IdentifierValues)...)
+			}
+		}
+	}
+	return concordancesOfRows(pageOfRows(rows, page), sd.publicAPIURL)
+}
+
+// ExportPage reads the concordances of the first limit identifierValues of the authority greater than after,
+// in the order of identifierValue. next is the identifierValue the following page starts after, empty on the last page.
+func (sd *SnapshotDriver) ExportPage(ctx context.Context, authority string, after string, limit int) (concordances Concordances, next string, err error) {
+	if err := ctx.Err(); err != nil {
+		return Concordances{}, "", err
+	}
+	name, known := AuthorityFromURI(authority)
+	if !known {
+		return Concordances{}, "", nil
+	}
+
+	idx := sd.index.Load()
+	values := idx.values[name]
+	start := sort.SearchStrings(values, after)
+	if start < len(values) && values[start] == after {
+		start++
+	}
+	end := min(start+limit, len(values))
+
+	concordances, _, err = concordancesOfRows(idx.authorityRows(sd.resolvers, name, values[start:end]), sd.publicAPIURL)
+	if err != nil {
+		return Concordances{}, "", err
+	}
+	concordances, next = exportedConcordances(concordances, limit)
+	return concordances, next, nil
+}
+
+// conceptIDRows reads the identifiers of the canonical concepts of the UPP identifiers, restricted to the given
+// authority URIs unless there are none. As in Cypher, the identifiers stored on the canonical concept are only read
+// for the source concepts labelled with the sourceLabel of their resolver.
+func (idx *snapshotIndex) conceptIDRows(resolvers *ResolverRegistry, identifiers []string, authorityURIs []string) []neoReadStruct {
+	var requested map[string]bool
+	if len(authorityURIs) > 0 {
+		requested = map[string]bool{}
+		for _, uri := range authorityURIs {
+			if authority, found := AuthorityFromURI(uri); found {
+				requested[authority] = true
+			}
+		}
+	}
+
+	var rows []neoReadStruct
+	for _, id := range identifiers {
+		for _, canonical := range idx.canonicals[uppResolver.Authority][id] {
+			inputType := InputTypeLeaf
+			if id == canonical {
+				inputType = InputTypeCanonical
+			}
+			for _, record := range idx.concepts[canonical] {
+				if requested != nil && !requested[record.Authority] {
+					continue
+				}
+				if resolver, _ := resolvers.registered(record.Authority); resolver.property != "" && !hasLabel(idx.sourceLabels(id, record), resolver.sourceLabel) {
+					continue
+				}
+				rows = append(rows, snapshotRow(record, id, inputType))
+			}
+		}
+	}
+	return rows
+}
+
+// sourceLabels are the labels of the source concept of the UPP identifier, or the labels of the canonical concept of
+// the record when the snapshot does not record them
+func (idx *snapshotIndex) sourceLabels(uuid string, record SnapshotRecord) []string {
+	if labels, found := idx.sourceTypes[uuid]; found {
+		return labels
+	}
+	return record.Types
+}
+
+// authorityRows reads the concepts holding one of the identifierValues of the authority, in the order of the values
+func (idx *snapshotIndex) authorityRows(resolvers *ResolverRegistry, authority string, identifierValues []string) []neoReadStruct {
+	resolver, _ := resolvers.registered(authority)
+	property := resolver.property != ""
+
+	var rows []neoReadStruct
+	for _, value := range identifierValues {
+		for _, canonical := range idx.canonicals[authority][value] {
+			inputType := InputTypeLeaf
+			if property || (authority == uppResolver.Authority && value == canonical) {
+				inputType = InputTypeCanonical
+			}
+			record := idx.concepts[canonical][0]
+			record.Authority, record.AuthorityValue = authority, value
+			rows = append(rows, snapshotRow(record, value, inputType))
+		}
+	}
+	return rows
+}
+
+func snapshotRow(record SnapshotRecord, input string, inputType string) neoReadStruct {
+	return neoReadStruct{
+		CanonicalUUID:  record.CanonicalUUID,
+		Types:          record.Types,
+		PrefLabel:      record.PrefLabel,
+		IsDeprecated:   record.IsDeprecated,
+		Input:          input,
+		InputType:      inputType,
+		Authority:      record.Authority,
+		AuthorityValue: record.AuthorityValue,
+	}
+}
@@ -0,0 +1,362 @@
+package concordances
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+	logger "github.com/Financial-Times/go-logger/v2"
+)
+
+// SnapshotSource pages through every canonical concept in the order of its UUID
+type SnapshotSource interface {
+	// SnapshotPage reads the records of the first limit canonical concepts whose UUID is greater than after.
+	// next is the canonical UUID the following page starts after, empty on the last page.
+	SnapshotPage(ctx context.Context, after string, limit int) (records []SnapshotRecord, next string, err error)
+}
+
+// canonicalsQuery reads the preferred source concept of the first limit canonical concepts whose UUID is greater than
+// after. A canonical concept without a source concept of its prefUUID is read with the source concept of the lowest
+// uuid instead, and with an empty UUID when it has no source concept at all.
+func canonicalsQuery(after string, limit int) *cmneo4j.Query {
+	return &cmneo4j.Query{
+		Cypher: `
+		MATCH (canonical:Concept)
+		WHERE exists(canonical.prefUUID) AND canonical.prefUUID > $after
+		WITH canonical
+		ORDER BY canonical.prefUUID
+		LIMIT $limit
+		OPTIONAL MATCH (p:Thing)-[:EQUIVALENT_TO]->(canonical)
+		WITH canonical, p
+		ORDER BY p.uuid = canonical.prefUUID DESC, p.uuid
+		RETURN canonical.prefUUID AS canonicalUUID, head(collect(p.uuid)) AS UUID`,
+		Params: map[string]interface{}{
+			"after": after,
+			"limit": limit,
+		},
+	}
+}
+
+// sourceTypesQuery reads the labels of the source concepts of the UPP identifiers
+func sourceTypesQuery(uuids []string) *cmneo4j.Query {
+	return &cmneo4j.Query{
+		Cypher: `
+		MATCH (p:Thing)
+		WHERE p.uuid IN $identifiers
+		RETURN p.uuid AS UUID, labels(p) AS types`,
+		Params: map[string]interface{}{
+			"identifiers": uuids,
+		},
+	}
+}
+
+// SnapshotPage reads the canonical concepts of the page, then their identifiers with the conceptId query of
+// every authority run for a single source concept of each, preferably the preferred one, which reads the identifiers
+// of all of their source concepts, and last the labels of their source concepts
+func (cd CypherDriver) SnapshotPage(ctx context.Context, after string, limit int) (records []SnapshotRecord, next string, err error) {
+	var canonicals []neoReadStruct
+	query := canonicalsQuery(after, limit)
+	query.Result = &canonicals
+	err = cd.read(ctx, querySpan{name: "concordances.snapshot.canonicals"}, query)
+	if errors.Is(err, cmneo4j.ErrNoResultsFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading canonical concepts after %q: %w", after, err)
+	}
+
+	var uuids []string
+	distinct := map[string]bool{}
+	for _, row := range canonicals {
+		if row.UUID != "" {
+			uuids = append(uuids, row.UUID)
+		}
+		distinct[row.CanonicalUUID] = true
+		next = max(next, row.CanonicalUUID)
+	}
+	if len(distinct) < limit {
+		next = ""
+	}
+	if len(uuids) == 0 {
+		return nil, next, nil
+	}
+
+	var rows []neoReadStruct
+	query = cd.resolvers.conceptIDQuery(uuids, nil)
+	query.Result = &rows
+	err = cd.read(ctx, querySpan{name: "concordances.snapshot.conceptId", identifiers: len(uuids)}, query)
+	if err != nil && !errors.Is(err, cmneo4j.ErrNoResultsFound) {
+		return nil, "", fmt.Errorf("error reading identifiers of canonical concepts after %q: %w", after, err)
+	}
+
+	var sources []string
+	for _, row := range rows {
+		if row.Authority == uppResolver.Authority {
+			sources = append(sources, row.AuthorityValue)
+		}
+	}
+	sourceTypes := map[string][]string{}
+	if len(sources) > 0 {
+		var labels []neoReadStruct
+		query = sourceTypesQuery(sources)
+		query.Result = &labels
+		err = cd.read(ctx, querySpan{name: "concordances.snapshot.sourceTypes", identifiers: len(sources)}, query)
+		if err != nil && !errors.Is(err, cmneo4j.ErrNoResultsFound) {
+			return nil, "", fmt.Errorf("error reading source concepts of canonical concepts after %q: %w", after, err)
+		}
+		for _, row := range labels {
+			sourceTypes[row.UUID] = row.Types
+		}
+	}
+	return recordsOfRows(rows, sourceTypes), next, nil
+}
+
+// SnapshotPage reads the records of the canonical concepts of the page as CypherDriver does
+func (md *MemoryDriver) SnapshotPage(ctx context.Context, after string, limit int) (records []SnapshotRecord, next string, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	start := sort.Search(len(md.concepts), func(i int) bool { return md.concepts[i].node.PrefUUID > after })
+	end := min(start+limit, len(md.concepts))
+	var uuids []string
+	for _, concept := range md.concepts[start:end] {
+		if leaf, found := md.leaves[concept.node.PrefUUID]; found && leaf.canonical == concept {
+			uuids = append(uuids, leaf.node.UUID)
+		} else if len(concept.leaves) > 0 {
+			uuids = append(uuids, concept.leaves[0].node.UUID)
+		}
+	}
+	if end-start == limit {
+		next = md.concepts[end-1].node.PrefUUID
+	}
+	rows := md.conceptIDRows(uuids, md.resolvers.selectForConceptIDs(nil))
+	sourceTypes := map[string][]string{}
+	for _, row := range rows {
+		if leaf, found := md.leaves[row.AuthorityValue]; found && row.Authority == uppResolver.Authority {
+			sourceTypes[row.AuthorityValue] = leaf.labels
+		}
+	}
+	return recordsOfRows(rows, sourceTypes), next, nil
+}
+
+// recordsOfRows are the distinct identifiers of the rows, in the order of canonical UUID, authority and identifierValue.
+// The UPP identifiers carry the sourceTypes of their source concept.
+func recordsOfRows(rows []neoReadStruct, sourceTypes map[string][]string) []SnapshotRecord {
+	var records []SnapshotRecord
+	seen := map[string]bool{}
+	for _, row := range rows {
+		key := row.CanonicalUUID + "\x00" + row.Authority + "\x00" + row.AuthorityValue
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		record := SnapshotRecord{
+			CanonicalUUID:  row.CanonicalUUID,
+			Types:          row.Types,
+			PrefLabel:      row.PrefLabel,
+			IsDeprecated:   row.IsDeprecated,
+			Authority:      row.Authority,
+			AuthorityValue: row.AuthorityValue,
+		}
+		if row.Authority == uppResolver.Authority {
+			record.SourceTypes = sourceTypes[row.AuthorityValue]
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.CanonicalUUID != b.CanonicalUUID {
+			return a.CanonicalUUID < b.CanonicalUUID
+		}
+		if a.Authority != b.Authority {
+			return a.Authority < b.Authority
+		}
+		return a.AuthorityValue < b.AuthorityValue
+	})
+	return records
+}
+
+// snapshotProgress is a line of the progress file of an export: the header, a record, or a checkpoint after which
+// every record of the canonical concepts up to After was written, Complete once they all were
+type snapshotProgress struct {
+	Header   *SnapshotHeader `json:"header,omitempty"`
+	Record   *SnapshotRecord `json:"record,omitempty"`
+	After    string          `json:"after,omitempty"`
+	Complete bool            `json:"complete,omitempty"`
+}
+
+// ExportSnapshot writes a snapshot of every canonical concept of the source to path, reading pageSize concepts at a time.
+// The records are written to a progress file next to path first, so that an interrupted export resumes after the
+// last page written unless restart is set, and the snapshot replaces path only once complete.
+// The source is read over time, so the snapshot is not taken at a single point in time.
+func ExportSnapshot(ctx context.Context, source SnapshotSource, path string, sourceName string, pageSize int, restart bool, log *logger.UPPLogger) error {
+	progressPath := path + ".progress"
+	if restart {
+		if err := os.Remove(progressPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	header, after, complete, err := resumeSnapshotProgress(progressPath)
+	resumed := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("resuming snapshot progress %s: %w", progressPath, err)
+	}
+	flags := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+	if !resumed {
+		flags |= os.O_TRUNC
+	}
+	progress, err := os.OpenFile(progressPath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	if resumed {
+		log.WithFields(map[string]interface{}{"after": after, "createdAt": header.CreatedAt}).Info("Resuming snapshot export")
+	} else {
+		header = SnapshotHeader{CreatedAt: time.Now().UTC(), Source: sourceName}
+		if err := appendSnapshotProgress(progress, snapshotProgress{Header: &header}); err != nil {
+			return fmt.Errorf("writing snapshot progress %s: %w", progressPath, err)
+		}
+	}
+
+	for !complete {
+		records, next, err := source.SnapshotPage(ctx, after, pageSize)
+		if err != nil {
+			return fmt.Errorf("exporting canonical concepts after %q: %w", after, err)
+		}
+		lines := make([]snapshotProgress, 0, len(records)+1)
+		for i := range records {
+			lines = append(lines, snapshotProgress{Record: &records[i]})
+		}
+		complete = next == ""
+		lines = append(lines, snapshotProgress{After: next, Complete: complete})
+		if err := appendSnapshotProgress(progress, lines...); err != nil {
+			return fmt.Errorf("writing snapshot progress %s: %w", progressPath, err)
+		}
+		log.WithFields(map[string]interface{}{"after": after, "records": len(records)}).Debug("Exported snapshot page")
+		after = next
+	}
+	if err := progress.Close(); err != nil {
+		return err
+	}
+
+	if err := writeSnapshotFromProgress(progressPath, path); err != nil {
+		return fmt.Errorf("writing snapshot %s: %w", path, err)
+	}
+	return os.Remove(progressPath)
+}
+
+// resumeSnapshotProgress reads the header of the progress file and its last checkpoint, dropping the records
+// following that checkpoint. It fails with os.ErrNotExist when there is no progress file or it has no header.
+func resumeSnapshotProgress(progressPath string) (header SnapshotHeader, after string, complete bool, err error) {
+	f, err := os.Open(progressPath)
+	if err != nil {
+		return SnapshotHeader{}, "", false, err
+	}
+	defer f.Close()
+
+	var checkpointEnd, offset int64
+	lines := bufio.NewReader(f)
+	for {
+		line, err := lines.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return SnapshotHeader{}, "", false, err
+		}
+		offset += int64(len(line))
+
+		var decoded snapshotProgress
+		if err := json.Unmarshal(line, &decoded); err != nil {
+			return SnapshotHeader{}, "", false, fmt.Errorf("decoding snapshot progress: %w", err)
+		}
+		switch {
+		case decoded.Header != nil:
+			header = *decoded.Header
+			checkpointEnd = offset
+		case decoded.Record == nil:
+			after, complete = decoded.After, decoded.Complete
+			checkpointEnd = offset
+		}
+	}
+	if header.CreatedAt.IsZero() {
+		return SnapshotHeader{}, "", false, os.ErrNotExist
+	}
+	return header, after, complete, os.Truncate(progressPath, checkpointEnd)
+}
+
+func appendSnapshotProgress(progress io.Writer, lines ...snapshotProgress) error {
+	w := bufio.NewWriter(progress)
+	encoder := json.NewEncoder(w)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// writeSnapshotFromProgress writes the snapshot of a complete progress file to a temporary file renamed to path,
+// so that a snapshot being served is never replaced by an incomplete one
+func writeSnapshotFromProgress(progressPath string, path string) error {
+	progress, err := os.Open(progressPath)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := tmp.Chmod(0o644); err != nil {
+		return err
+	}
+
+	var sw *SnapshotWriter
+	lines := bufio.NewScanner(progress)
+	lines.Buffer(nil, 1024*1024)
+	for lines.Scan() {
+		var decoded snapshotProgress
+		if err := json.Unmarshal(lines.Bytes(), &decoded); err != nil {
+			return err
+		}
+		switch {
+		case decoded.Header != nil:
+			if sw, err = NewSnapshotWriter(tmp, *decoded.Header); err != nil {
+				return err
+			}
+		case decoded.Record != nil:
+			if err := sw.Write(*decoded.Record); err != nil {
+				return err
+			}
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return err
+	}
+	if err := sw.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,168 @@
+package concordances
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// interruptedSource fails once it has read failAfter pages, recording where every page it read started
+type interruptedSource struct {
+	source    SnapshotSource
+	failAfter int
+	afters    []string
+}
+
+func (s *interruptedSource) SnapshotPage(ctx context.Context, after string, limit int) ([]SnapshotRecord, string, error) {
+	if s.failAfter >= 0 && len(s.afters) == s.failAfter {
+		return nil, "", errors.New("connection lost")
+	}
+	s.afters = append(s.afters, after)
+	return s.source.SnapshotPage(ctx, after, limit)
+}
+
+var testLog = logger.NewUPPLogger("test-service", "panic")
+
+func readSnapshotFile(t *testing.T, path string) (SnapshotHeader, []SnapshotRecord) {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	header, records, err := ReadSnapshot(f)
+	assert.NoError(t, err)
+	return header, records
+}
+
+func TestExportSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concordances.snapshot.gz")
+	source := &interruptedSource{source: loadFixtures(t), failAfter: -1}
+
+	err := ExportSnapshot(context.Background(), source, path, "fixtures", 4, false, testLog)
+	assert.NoError(t, err)
+
+	header, records := readSnapshotFile(t, path)
+	assert.Equal(t, "fixtures", header.Source)
+	assert.Contains(t, records, SnapshotRecord{
+		CanonicalUUID:  "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		Types:          []string{"Thing", "Concept", "Organisation"},
+		PrefLabel:      "Bank of Test",
+		Authority:      "LEI",
+		AuthorityValue: "VNF516RB4DFV5NQ22UF0",
+	})
+	assert.Contains(t, records, SnapshotRecord{
+		CanonicalUUID:  "5aba454b-3e31-31b9-bdeb-0caf83f62b44",
+		Types:          []string{"Thing", "Concept", "Location"},
+		PrefLabel:      "Romania",
+		Authority:      "UPP",
+		AuthorityValue: "4411b761-e632-30e7-855c-06aeca76c48d",
+		SourceTypes:    []string{"Thing", "Concept", "Location"},
+	})
+	assert.Len(t, source.afters, 4)
+	assert.NoFileExists(t, path+".progress")
+
+	driver, err := NewSnapshotDriver("http://api.ft.com", path)
+	assert.NoError(t, err)
+	concordances, found, err := driver.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{bankOfTestID + " http://api.ft.com/system/FACTSET 7IV872-E"}, identifiers(concordances.Concordance))
+}
+
+func TestExportSnapshotResumesAfterTheLastPageWritten(t *testing.T) {
+	dir := t.TempDir()
+	complete := filepath.Join(dir, "complete.snapshot.gz")
+	assert.NoError(t, ExportSnapshot(context.Background(), loadFixtures(t), complete, "fixtures", 4, false, testLog))
+	_, expected := readSnapshotFile(t, complete)
+
+	path := filepath.Join(dir, "resumed.snapshot.gz")
+	interrupted := &interruptedSource{source: loadFixtures(t), failAfter: 2}
+	err := ExportSnapshot(context.Background(), interrupted, path, "fixtures", 4, false, testLog)
+	assert.Error(t, err)
+	assert.NoFileExists(t, path)
+
+	// a record written by the interrupted export after its last checkpoint
+	progress, err := os.OpenFile(path+".progress", os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = progress.WriteString(`{"record":{"canonicalUUID":"ffffffff-ffff-ffff-ffff-ffffffffffff","types":["Thing"],"authority":"UPP","authorityValue":"x"}}` + "\n" + `{"rec`)
+	assert.NoError(t, err)
+	assert.NoError(t, progress.Close())
+
+	resumed := &interruptedSource{source: loadFixtures(t), failAfter: -1}
+	err = ExportSnapshot(context.Background(), resumed, path, "ignored", 4, false, testLog)
+	assert.NoError(t, err)
+	assert.Less(t, interrupted.afters[1], resumed.afters[0])
+	assert.Len(t, resumed.afters, 2)
+
+	header, records := readSnapshotFile(t, path)
+	assert.Equal(t, "fixtures", header.Source)
+	assert.Equal(t, expected, records)
+}
+
+func TestExportSnapshotRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concordances.snapshot.gz")
+	interrupted := &interruptedSource{source: loadFixtures(t), failAfter: 1}
+	assert.Error(t, ExportSnapshot(context.Background(), interrupted, path, "first", 4, false, testLog))
+
+	restarted := &interruptedSource{source: loadFixtures(t), failAfter: -1}
+	assert.NoError(t, ExportSnapshot(context.Background(), restarted, path, "second", 4, true, testLog))
+	assert.Equal(t, "", restarted.afters[0])
+
+	header, _ := readSnapshotFile(t, path)
+	assert.Equal(t, "second", header.Source)
+}
+
+func TestCypherDriverSnapshotPage(t *testing.T) {
+	rows := make([]neoReadStruct, len(bankOfTestRows))
+	copy(rows, bankOfTestRows)
+	rows[0].UUID = "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"
+	rows = append(rows, neoReadStruct{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: rows[0].Types, Authority: "UPP", AuthorityValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"})
+	fake := &fakeNeoDriver{rows: rows}
+	undertest, err := newCypherDriver(fake, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+
+	records, next, err := undertest.SnapshotPage(context.Background(), "", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", next)
+	assert.Len(t, records, 3)
+	assert.Equal(t, 3, fake.executions)
+	assert.Contains(t, fake.queries[0].Cypher, "ORDER BY p.uuid = canonical.prefUUID DESC, p.uuid")
+	assert.Equal(t, []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, fake.queries[1].Params["identifiers"], "the identifiers should only be read for the preferred source concept")
+	assert.Equal(t, []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, fake.queries[2].Params["identifiers"], "the labels should be read for the source concepts of the UPP identifiers")
+	assert.Equal(t, []string{"Thing", "Concept", "Organisation"}, records[2].SourceTypes)
+
+	_, next, err = undertest.SnapshotPage(context.Background(), "", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+}
+
+func TestMemoryDriverSnapshotPageReadsConceptsWithoutTheirPreferredSourceConcept(t *testing.T) {
+	driver, err := NewMemoryDriver("http://api.ft.com", CanonicalNode{
+		PrefUUID: "11111111-1111-1111-1111-111111111111",
+		Type:     "Organisation",
+		SourceRepresentations: []SourceNode{
+			{UUID: "33333333-3333-3333-3333-333333333333", Type: "Organisation", Authority: "FACTSET", AuthorityValue: "000003-E"},
+			{UUID: "22222222-2222-2222-2222-222222222222", Type: "Organisation", Authority: "FACTSET", AuthorityValue: "000002-E"},
+		},
+	})
+	assert.NoError(t, err)
+
+	records, next, err := driver.SnapshotPage(context.Background(), "", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	var values []string
+	for _, record := range records {
+		assert.Equal(t, "11111111-1111-1111-1111-111111111111", record.CanonicalUUID)
+		values = append(values, record.Authority+" "+record.AuthorityValue)
+	}
+	assert.Equal(t, []string{
+		"FACTSET 000002-E",
+		"FACTSET 000003-E",
+		"UPP 22222222-2222-2222-2222-222222222222",
+		"UPP 33333333-3333-3333-3333-333333333333",
+	}, values)
+}
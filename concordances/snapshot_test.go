@@ -0,0 +1,293 @@
+package concordances
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var snapshotRecords = []SnapshotRecord{
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "UPP", AuthorityValue: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"},
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "UPP", AuthorityValue: "2cdeb859-70df-3a0e-b125-f958366bea44"},
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "FACTSET", AuthorityValue: "7IV872-E"},
+	{CanonicalUUID: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", Types: []string{"Thing", "Concept", "Organisation"}, PrefLabel: "Bank of Test", Authority: "LEI", AuthorityValue: "VNF516RB4DFV5NQ22UF0"},
+	{CanonicalUUID: "5aba454b-3e31-31b9-bdeb-0caf83f62b44", Types: []string{"Thing", "Concept", "Location"}, PrefLabel: "Romania", Authority: "UPP", AuthorityValue: "4411b761-e632-30e7-855c-06aeca76c48d"},
+	{CanonicalUUID: "5aba454b-3e31-31b9-bdeb-0caf83f62b44", Types: []string{"Thing", "Concept", "Location"}, PrefLabel: "Romania", Authority: "ISO-3166-1", AuthorityValue: "RO"},
+	{CanonicalUUID: "5aba454b-3e31-31b9-bdeb-0caf83f62b44", Types: []string{"Thing", "Concept", "Location"}, PrefLabel: "Romania", Authority: "TME", AuthorityValue: "TnN0ZWluX0dMX1JP-R0w="},
+}
+
+func encodeSnapshot(t *testing.T, createdAt time.Time, records []SnapshotRecord) []byte {
+	var buf bytes.Buffer
+	sw, err := NewSnapshotWriter(&buf, SnapshotHeader{CreatedAt: createdAt, Source: "test"})
+	assert.NoError(t, err)
+	for _, record := range records {
+		assert.NoError(t, sw.Write(record))
+	}
+	assert.NoError(t, sw.Close())
+	return buf.Bytes()
+}
+
+func writeSnapshotFile(t *testing.T, path string, createdAt time.Time, records []SnapshotRecord) {
+	assert.NoError(t, os.WriteFile(path, encodeSnapshot(t, createdAt, records), 0o600))
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(gz)
+	assert.NoError(t, err)
+	return buf.String()
+}
+
+func regzip(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	header, records, err := ReadSnapshot(bytes.NewReader(encodeSnapshot(t, createdAt, snapshotRecords)))
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotHeader{Version: SnapshotVersion, CreatedAt: createdAt, Source: "test"}, header)
+	assert.Equal(t, snapshotRecords, records)
+}
+
+func TestReadSnapshotRejectsCorruptSnapshots(t *testing.T) {
+	content := gunzip(t, encodeSnapshot(t, time.Now(), snapshotRecords))
+	lines := strings.SplitAfter(content, "\n")
+
+	tests := []struct {
+		name    string
+		content []byte
+		err     string
+	}{
+		{name: "not compressed", content: []byte(content), err: "decompressing snapshot"},
+		{name: "no trailer", content: regzip(t, strings.Join(lines[:len(lines)-2], "")), err: "snapshot is truncated"},
+		{name: "altered record", content: regzip(t, strings.Replace(content, "7IV872-E", "7IV872-X", 1)), err: "snapshot checksum mismatch"},
+		{name: "missing record", content: regzip(t, lines[0]+strings.Join(lines[2:], "")), err: "snapshot checksum mismatch"},
+		{name: "unknown version", content: regzip(t, strings.Replace(content, `"version":1`, `"version":2`, 1)), err: "unsupported snapshot version 2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ReadSnapshot(bytes.NewReader(test.content))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.err)
+			}
+		})
+	}
+}
+
+func newTestSnapshotDriver(t *testing.T) (*SnapshotDriver, string) {
+	path := filepath.Join(t.TempDir(), "concordances.snapshot.gz")
+	writeSnapshotFile(t, path, time.Now(), snapshotRecords)
+	driver, err := NewSnapshotDriver("http://api.ft.com", path)
+	assert.NoError(t, err)
+	return driver, path
+}
+
+func TestSnapshotDriverReadByConceptID(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+
+	concordances, found, err := driver.ReadByConceptID(context.Background(), []string{bankOfTestLeaf}, []string{"http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{
+		bankOfTestID + " http://api.ft.com/system/FACTSET 7IV872-E",
+		bankOfTestID + " http://api.ft.com/system/LEI VNF516RB4DFV5NQ22UF0",
+	}, identifiers(concordances.Concordance))
+	for _, c := range concordances.Concordance {
+		assert.Equal(t, bankOfTestLeaf, c.Input)
+		assert.Equal(t, InputTypeLeaf, c.InputType)
+		assert.Equal(t, "Bank of Test", c.Concept.PrefLabel)
+	}
+
+	concordances, found, err = driver.ReadByConceptID(context.Background(), []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Len(t, concordances.Concordance, 4)
+	assert.Equal(t, InputTypeCanonical, concordances.Concordance[0].InputType)
+
+	_, found, err = driver.ReadByConceptID(context.Background(), []string{"00000000-0000-0000-0000-000000000000"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestSnapshotDriverReadsConceptIDsAsCypher(t *testing.T) {
+	nodes, err := loadCanonicalNodes("./fixtures")
+	assert.NoError(t, err)
+	// the identifiers stored on a Location are not read for its source concepts that are not locations
+	nodes = append(nodes, CanonicalNode{
+		PrefUUID:   "77777777-7777-7777-7777-777777777777",
+		Type:       "Location",
+		Properties: map[string]string{"iso31661": "ZZ"},
+		SourceRepresentations: []SourceNode{
+			{UUID: "77777777-7777-7777-7777-777777777777", Type: "Location"},
+			{UUID: "88888888-8888-8888-8888-888888888888", Type: "Concept", Authority: "FACTSET", AuthorityValue: "ZZZZZZ-E"},
+		},
+	})
+	memory, err := NewMemoryDriver("http://api.ft.com", nodes...)
+	assert.NoError(t, err)
+	records, err := CollectSnapshot(context.Background(), memory, 3)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "concordances.snapshot.gz")
+	writeSnapshotFile(t, path, time.Now(), records)
+	snapshot, err := NewSnapshotDriver("http://api.ft.com", path)
+	assert.NoError(t, err)
+
+	for uuid := range memory.leaves {
+		expected, _, err := memory.ReadByConceptID(context.Background(), []string{uuid}, nil)
+		assert.NoError(t, err)
+		actual, _, err := snapshot.ReadByConceptID(context.Background(), []string{uuid}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, identifiers(expected.Concordance), identifiers(actual.Concordance), uuid)
+	}
+
+	actual, _, err := snapshot.ReadByConceptID(context.Background(), []string{"88888888-8888-8888-8888-888888888888"}, []string{"http://api.ft.com/system/ISO-3166-1"})
+	assert.NoError(t, err)
+	assert.Empty(t, actual.Concordance)
+}
+
+func TestSnapshotDriverReadByAuthority(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+
+	tests := []struct {
+		authority string
+		value     string
+		conceptID string
+		inputType string
+	}{
+		{authority: "http://api.ft.com/system/FACTSET", value: "7IV872-E", conceptID: bankOfTestID, inputType: InputTypeLeaf},
+		{authority: "http://api.ft.com/system/LEI", value: "VNF516RB4DFV5NQ22UF0", conceptID: bankOfTestID, inputType: InputTypeCanonical},
+		{authority: "http://api.ft.com/system/ISO-3166-1", value: "RO", conceptID: romaniaID, inputType: InputTypeCanonical},
+		{authority: "http://api.ft.com/system/UPP", value: "4411b761-e632-30e7-855c-06aeca76c48d", conceptID: romaniaID, inputType: InputTypeLeaf},
+		{authority: "http://api.ft.com/system/UPP", value: "cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", conceptID: bankOfTestID, inputType: InputTypeCanonical},
+	}
+	for _, test := range tests {
+		t.Run(test.authority+" "+test.value, func(t *testing.T) {
+			concordances, found, err := driver.ReadByAuthority(context.Background(), test.authority, []string{test.value, "unknown"})
+			assert.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, []string{test.conceptID + " " + test.authority + " " + test.value}, identifiers(concordances.Concordance))
+			assert.Equal(t, test.inputType, concordances.Concordance[0].InputType)
+		})
+	}
+}
+
+func TestSnapshotDriverTranslate(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+
+	translations, found, err := driver.Translate(context.Background(), "http://api.ft.com/system/FACTSET", "http://api.ft.com/system/LEI", []string{"7IV872-E", "unknown"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []Translation{{
+		From:    Identifier{Authority: "http://api.ft.com/system/FACTSET", IdentifierValue: "7IV872-E"},
+		To:      Identifier{Authority: "http://api.ft.com/system/LEI", IdentifierValue: "VNF516RB4DFV5NQ22UF0"},
+		Concept: translations[0].Concept,
+	}}, translations)
+	assert.Equal(t, bankOfTestID, translations[0].Concept.ID)
+}
+
+func TestSnapshotDriverExportPage(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+
+	page, next, err := driver.ExportPage(context.Background(), "http://api.ft.com/system/UPP", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "4411b761-e632-30e7-855c-06aeca76c48d", next)
+	assert.Equal(t, []string{
+		romaniaID + " http://api.ft.com/system/UPP 4411b761-e632-30e7-855c-06aeca76c48d",
+		bankOfTestID + " http://api.ft.com/system/UPP 2cdeb859-70df-3a0e-b125-f958366bea44",
+	}, identifiers(page.Concordance))
+
+	page, next, err = driver.ExportPage(context.Background(), "http://api.ft.com/system/UPP", next, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, []string{bankOfTestID + " http://api.ft.com/system/UPP cd7e4345-f11f-41f3-a0f0-2cf5c43e0115"}, identifiers(page.Concordance))
+}
+
+func TestSnapshotDriverReadPage(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+	lookup := Lookup{Groups: []AuthorityIdentifiers{
+		{Authority: "http://api.ft.com/system/UPP", IdentifierValues: []string{"4411b761-e632-30e7-855c-06aeca76c48d", bankOfTestLeaf}},
+		{Authority: "http://api.ft.com/system/ISO-3166-1", IdentifierValues: []string{"RO"}},
+	}}
+
+	first, found, err := driver.ReadPage(context.Background(), lookup, Page{Limit: 1})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{romaniaID + " http://api.ft.com/system/ISO-3166-1 RO"}, identifiers(first.Concordance))
+
+	after := positionOf(first.Concordance[0])
+	page, found, err := driver.ReadPage(context.Background(), lookup, Page{After: &after, Limit: 2})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{
+		romaniaID + " http://api.ft.com/system/UPP 4411b761-e632-30e7-855c-06aeca76c48d",
+		bankOfTestID + " http://api.ft.com/system/UPP 2cdeb859-70df-3a0e-b125-f958366bea44",
+	}, identifiers(page.Concordance))
+}
+
+func TestSnapshotDriverKeepsServingWhenReloadFails(t *testing.T) {
+	driver, path := newTestSnapshotDriver(t)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a snapshot"), 0o600))
+	assert.Error(t, driver.Reload())
+
+	_, found, err := driver.ReadByAuthority(context.Background(), "http://api.ft.com/system/LEI", []string{"VNF516RB4DFV5NQ22UF0"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestSnapshotDriverWatchReloadsChangedFile(t *testing.T) {
+	driver, path := newTestSnapshotDriver(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go driver.Watch(ctx, 10*time.Millisecond, logger.NewUPPLogger("test-service", "panic"))
+
+	createdAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	writeSnapshotFile(t, path, createdAt, snapshotRecords[:1])
+
+	assert.Eventually(t, func() bool { return driver.CreatedAt().Equal(createdAt) }, time.Second, 10*time.Millisecond)
+	_, found, err := driver.ReadByAuthority(context.Background(), "http://api.ft.com/system/LEI", []string{"VNF516RB4DFV5NQ22UF0"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestSnapshotDriverAgeCheck(t *testing.T) {
+	driver, _ := newTestSnapshotDriver(t)
+
+	_, err := driver.AgeCheck(time.Hour).Checker()
+	assert.NoError(t, err)
+	_, err = driver.AgeCheck(-time.Hour).Checker()
+	assert.Error(t, err)
+}
+
+func TestSnapshotDriverValidityCheck(t *testing.T) {
+	driver, path := newTestSnapshotDriver(t)
+
+	_, err := driver.ValidityCheck().Checker()
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a snapshot"), 0o600))
+	assert.Error(t, driver.Reload())
+	_, err = driver.ValidityCheck().Checker()
+	assert.Error(t, err)
+
+	writeSnapshotFile(t, path, time.Now(), snapshotRecords)
+	assert.NoError(t, driver.Reload())
+	_, err = driver.ValidityCheck().Checker()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,89 @@
+package concordances
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/Financial-Times/public-concordances-api/concordances"
+
+	authorityAttribute       = attribute.Key("concordances.authority")
+	identifierCountAttribute = attribute.Key("concordances.identifier_count")
+	rowCountAttribute        = attribute.Key("db.response.returned_rows")
+	transactionIDAttribute   = attribute.Key("transaction_id")
+)
+
+// querySpan describes a Cypher query for its span: the authorities it looks up or is restricted to, none for any
+// authority, and the number of identifiers it looks up
+type querySpan struct {
+	name        string
+	authorities []string
+	identifiers int
+}
+
+// lookupSpan describes the query of a page of the lookup
+func lookupSpan(lookup Lookup) querySpan {
+	s := querySpan{name: "concordances.page", authorities: lookup.Authorities, identifiers: len(lookup.ConceptIDs)}
+	for _, group := range lookup.Groups {
+		s.authorities = append(s.authorities, group.Authority)
+		s.identifiers += len(group.IdentifierValues)
+	}
+	return s
+}
+
+// TracingHandler starts a span for every request, as a child of the span of its W3C traceparent header if any,
+// recording its transaction ID. The spans of the Cypher queries run for the request are children of that span.
+func TracingHandler(next http.Handler) http.Handler {
+	withTransactionID := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		trace.SpanFromContext(r.Context()).SetAttributes(transactionIDAttribute.String(tid))
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(withTransactionID, "concordances",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
+
+// startQuerySpan starts the span of a Cypher query, ended by endQuerySpan once the query was run
+func startQuerySpan(ctx context.Context, s querySpan) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", "neo4j"),
+		identifierCountAttribute.Int(s.identifiers),
+	}
+	if len(s.authorities) > 0 {
+		attributes = append(attributes, authorityAttribute.StringSlice(s.authorities))
+	}
+	return otel.Tracer(tracerName).Start(ctx, s.name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+// endQuerySpan records the number of rows the query read, or its error. No results found is not an error.
+// The rows are only counted once the query completed, an abandoned query may still be writing them.
+func endQuerySpan(span trace.Span, q *cmneo4j.Query, err error) {
+	defer span.End()
+	switch {
+	case errors.Is(err, cmneo4j.ErrNoResultsFound):
+		span.SetAttributes(rowCountAttribute.Int(0))
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	default:
+		if rows, ok := q.Result.(*[]neoReadStruct); ok {
+			span.SetAttributes(rowCountAttribute.Int(len(*rows)))
+		}
+	}
+}
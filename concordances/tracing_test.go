@@ -0,0 +1,108 @@
+package concordances
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cmneo4j "github.com/Financial-Times/cm-neo4j-driver"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans records the spans ended during the test and propagates W3C traceparent headers
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestCypherDriverQuerySpans(t *testing.T) {
+	tests := []struct {
+		name   string
+		fake   *fakeNeoDriver
+		rows   int64
+		status codes.Code
+	}{
+		{name: "Found", fake: &fakeNeoDriver{rows: bankOfTestRows}, rows: 2, status: codes.Unset},
+		{name: "NotFound", fake: &fakeNeoDriver{err: cmneo4j.ErrNoResultsFound}, rows: 0, status: codes.Unset},
+		{name: "Failed", fake: &fakeNeoDriver{err: errors.New("connection lost")}, rows: -1, status: codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			undertest, err := newCypherDriver(test.fake, "http://api.ft.com", 0, metrics.NewRegistry())
+			assert.NoError(t, err)
+
+			_, _, _ = undertest.ReadByAuthority(context.Background(), "http://api.ft.com/system/FACTSET", []string{"7IV872-E", "000001-E"})
+
+			spans := recorder.Ended()
+			if !assert.Len(t, spans, 1) {
+				return
+			}
+			assert.Equal(t, "concordances.authority", spans[0].Name())
+			assert.Equal(t, test.status, spans[0].Status().Code)
+			attributes := spanAttributes(spans[0])
+			assert.Equal(t, []string{"http://api.ft.com/system/FACTSET"}, attributes[authorityAttribute].AsStringSlice())
+			assert.EqualValues(t, 2, attributes[identifierCountAttribute].AsInt64())
+			rows, counted := attributes[rowCountAttribute]
+			assert.Equal(t, test.rows >= 0, counted)
+			if counted {
+				assert.Equal(t, test.rows, rows.AsInt64())
+			}
+		})
+	}
+}
+
+func TestTracingHandler(t *testing.T) {
+	recorder := recordSpans(t)
+	undertest, err := newCypherDriver(&fakeNeoDriver{rows: bankOfTestRows}, "http://api.ft.com", 0, metrics.NewRegistry())
+	assert.NoError(t, err)
+	handler := TracingHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := undertest.ReadByConceptID(r.Context(), []string{bankOfTestID}, nil)
+		assert.NoError(t, err)
+	}))
+
+	req := httptest.NewRequest("GET", "/concordances?conceptId="+bankOfTestID, nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(transactionidutils.TransactionIDHeader, "tid_tracing")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	query, request := spans[0], spans[1]
+	assert.Equal(t, "GET /concordances", request.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent().SpanID().String())
+	assert.Equal(t, "tid_tracing", spanAttributes(request)[transactionIDAttribute].AsString())
+
+	assert.Equal(t, "concordances.conceptId", query.Name())
+	assert.Equal(t, request.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.EqualValues(t, 1, spanAttributes(query)[identifierCountAttribute].AsInt64())
+}
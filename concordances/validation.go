@@ -0,0 +1,94 @@
+package concordances
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	invalidConceptID             = "must be a UUID or a thing URI ending with one"
+	emptyIdentifierValue         = "must not be empty"
+	authorityWithoutIdentifier   = "at least one identifierValue is required for the authority"
+	invalidIdentifierValueFormat = "does not match the identifier format of the authority"
+	invalidInputs                = "some of the requested identifiers are invalid"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// InvalidInput describes a single requested identifier rejected by validation
+type InvalidInput struct {
+	Parameter string `json:"parameter"`
+	Value     string `json:"value"`
+	Reason    string `json:"reason"`
+}
+
+// validateConceptIDs checks every conceptId is a UUID, optionally given as a thing URI,
+// and returns the distinct UUIDs requested along with every invalid conceptId
+func validateConceptIDs(conceptIDs []string) ([]string, []InvalidInput) {
+	var uuids []string
+	var invalid []InvalidInput
+	seen := map[string]bool{}
+
+	for _, id := range conceptIDs {
+		uuid := strings.TrimPrefix(id, thingURIPrefix)
+		if !uuidPattern.MatchString(uuid) {
+			invalid = append(invalid, InvalidInput{Parameter: "conceptId", Value: id, Reason: invalidConceptID})
+			continue
+		}
+		if seen[uuid] {
+			continue
+		}
+		seen[uuid] = true
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids, invalid
+}
+
+// validateAuthorityGroups checks every authority has identifierValues in the format it issues them in,
+// and returns the groups without duplicate identifierValues along with every invalid input
+func validateAuthorityGroups(groups []AuthorityIdentifiers) ([]AuthorityIdentifiers, []InvalidInput) {
+	var valid []AuthorityIdentifiers
+	var invalid []InvalidInput
+
+	for _, group := range groups {
+		if len(group.IdentifierValues) == 0 {
+			invalid = append(invalid, InvalidInput{Parameter: "authority", Value: group.Authority, Reason: authorityWithoutIdentifier})
+			continue
+		}
+
+		pattern := identifierValuePatternOf(group.Authority)
+		values := []string{}
+		seen := map[string]bool{}
+		for _, value := range group.IdentifierValues {
+			switch {
+			case value == "":
+				invalid = append(invalid, InvalidInput{Parameter: "identifierValue", Value: value, Reason: emptyIdentifierValue})
+			case pattern != nil && !pattern.MatchString(value):
+				invalid = append(invalid, InvalidInput{Parameter: "identifierValue", Value: value, Reason: invalidIdentifierValueFormat})
+			case !seen[value]:
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+		valid = append(valid, AuthorityIdentifiers{Authority: group.Authority, IdentifierValues: values})
+	}
+
+	return valid, invalid
+}
+
+func identifierValuePatternOf(authorityURI string) *regexp.Regexp {
+	authority, found := AuthorityFromURI(authorityURI)
+	if !found {
+		return nil
+	}
+	return defaultResolvers.Resolver(authority).IdentifierPattern
+}
+
+func countIdentifiers(conceptUuids []string, groups []AuthorityIdentifiers) int {
+	count := len(conceptUuids)
+	for _, group := range groups {
+		count += len(group.IdentifierValues)
+	}
+	return count
+}
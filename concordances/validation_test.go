@@ -0,0 +1,63 @@
+package concordances
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConceptIDs(t *testing.T) {
+	uuids, invalid := validateConceptIDs([]string{
+		"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		"http://api.ft.com/things/cd7e4345-f11f-41f3-a0f0-2cf5c43e0115",
+		"http://api.ft.com/things/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
+		"http://api.ft.com/organisations/5aba454b-3e31-31b9-bdeb-0caf83f62b44",
+		"cd7e4345-f11f-41f3-a0f0",
+		"",
+	})
+
+	assert.Equal(t, []string{"cd7e4345-f11f-41f3-a0f0-2cf5c43e0115", "5aba454b-3e31-31b9-bdeb-0caf83f62b44"}, uuids)
+	assert.Equal(t, []InvalidInput{
+		{Parameter: "conceptId", Value: "http://api.ft.com/organisations/5aba454b-3e31-31b9-bdeb-0caf83f62b44", Reason: invalidConceptID},
+		{Parameter: "conceptId", Value: "cd7e4345-f11f-41f3-a0f0", Reason: invalidConceptID},
+		{Parameter: "conceptId", Value: "", Reason: invalidConceptID},
+	}, invalid)
+}
+
+func TestValidateAuthorityGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		authority string
+		values    []string
+		valid     []string
+		invalid   []string
+	}{
+		{name: "LEI", authority: "http://api.ft.com/system/LEI", values: []string{"VNF516RB4DFV5NQ22UF0", "VNF516RB4DFV5NQ22U", "vnf516rb4dfv5nq22uf0"}, valid: []string{"VNF516RB4DFV5NQ22UF0"}, invalid: []string{"VNF516RB4DFV5NQ22U", "vnf516rb4dfv5nq22uf0"}},
+		{name: "ISO31661", authority: "http://api.ft.com/system/ISO-3166-1", values: []string{"RO", "ROU", "ro"}, valid: []string{"RO"}, invalid: []string{"ROU", "ro"}},
+		{name: "NAICS", authority: "http://api.ft.com/system/NAICS", values: []string{"5111", "51", "5111A", "1234567"}, valid: []string{"5111", "51"}, invalid: []string{"5111A", "1234567"}},
+		{name: "ISIN", authority: "http://api.ft.com/system/ISIN", values: []string{"GB00BTST1234", "GB00BTST123A", "gb00btst1234"}, valid: []string{"GB00BTST1234"}, invalid: []string{"GB00BTST123A", "gb00btst1234"}},
+		{name: "FIGI", authority: "http://api.ft.com/system/FIGI", values: []string{"BBG000BTST12", "BBG000BTSA12", "BBX000BTST12"}, valid: []string{"BBG000BTST12"}, invalid: []string{"BBG000BTSA12", "BBX000BTST12"}},
+		{name: "Ticker", authority: "http://api.ft.com/system/TICKER", values: []string{"BOT", "BOT.L"}, valid: []string{"BOT", "BOT.L"}},
+		{name: "UPP", authority: "http://api.ft.com/system/UPP", values: []string{"d56e7388-25cb-343e-aea9-8b512e28476e", "d56e7388"}, valid: []string{"d56e7388-25cb-343e-aea9-8b512e28476e"}, invalid: []string{"d56e7388"}},
+		{name: "FreeFormat", authority: "http://api.ft.com/system/FACTSET", values: []string{"7IV872-E", "7IV872-E", ""}, valid: []string{"7IV872-E"}, invalid: []string{""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			groups, invalid := validateAuthorityGroups([]AuthorityIdentifiers{{Authority: test.authority, IdentifierValues: test.values}})
+			assert.Equal(t, []AuthorityIdentifiers{{Authority: test.authority, IdentifierValues: test.valid}}, groups)
+
+			var invalidValues []string
+			for _, i := range invalid {
+				assert.Equal(t, "identifierValue", i.Parameter)
+				invalidValues = append(invalidValues, i.Value)
+			}
+			assert.Equal(t, test.invalid, invalidValues)
+		})
+	}
+}
+
+func TestValidateAuthorityGroupsRejectsAuthorityWithoutIdentifierValues(t *testing.T) {
+	_, invalid := validateAuthorityGroups([]AuthorityIdentifiers{{Authority: "http://api.ft.com/system/FACTSET"}})
+	assert.Equal(t, []InvalidInput{{Parameter: "authority", Value: "http://api.ft.com/system/FACTSET", Reason: authorityWithoutIdentifier}}, invalid)
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -22,6 +25,13 @@ import (
 	"github.com/gorilla/mux"
 	cli "github.com/jawher/mow.cli"
 	"github.com/rcrowley/go-metrics"
+	"github.com/rcrowley/go-metrics/exp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 const (
@@ -37,6 +47,36 @@ func main() {
 		Desc:   "System Code of the application",
 		EnvVar: "APP_SYSTEM_CODE",
 	})
+	backend := app.String(cli.StringOpt{
+		Name:   "backend",
+		Value:  "neo4j",
+		Desc:   "Where concordances are read from: neo4j, snapshot to serve the concordance snapshot of snapshot-file, or memory to serve the concept JSON files of fixtures-dir for local development",
+		EnvVar: "BACKEND",
+	})
+	fixturesDir := app.String(cli.StringOpt{
+		Name:   "fixtures-dir",
+		Value:  "./concordances/fixtures",
+		Desc:   "Directory of the concept JSON files served by the memory backend",
+		EnvVar: "FIXTURES_DIR",
+	})
+	snapshotFile := app.String(cli.StringOpt{
+		Name:   "snapshot-file",
+		Value:  "",
+		Desc:   "Concordance snapshot served by the snapshot backend, it is reloaded whenever it changes",
+		EnvVar: "SNAPSHOT_FILE",
+	})
+	snapshotReloadInterval := app.String(cli.StringOpt{
+		Name:   "snapshot-reload-interval",
+		Value:  "30s",
+		Desc:   "How often the snapshot file is checked for changes. e.g. 1m",
+		EnvVar: "SNAPSHOT_RELOAD_INTERVAL",
+	})
+	snapshotMaxAge := app.String(cli.StringOpt{
+		Name:   "snapshot-max-age",
+		Value:  "24h",
+		Desc:   "Age of the snapshot served beyond which the healthcheck fails. e.g. 36h",
+		EnvVar: "SNAPSHOT_MAX_AGE",
+	})
 	neoURL := app.String(cli.StringOpt{
 		Name:   "neo-url",
 		Value:  "bolt://localhost:7687",
@@ -61,6 +101,48 @@ func main() {
 		Desc:   "Duration Get requests should be cached for. e.g. 2h45m would set the max-age value to '7440' seconds",
 		EnvVar: "CACHE_DURATION",
 	})
+	queryTimeout := app.String(cli.StringOpt{
+		Name:   "query-timeout",
+		Value:  "10s",
+		Desc:   "Maximum duration of a single Neo4j query, requests exceeding it are answered with 504. e.g. 1m30s",
+		EnvVar: "QUERY_TIMEOUT",
+	})
+	lookupCacheTTL := app.String(cli.StringOpt{
+		Name:   "lookup-cache-ttl",
+		Value:  "0s",
+		Desc:   "Duration concordances read from Neo4j are cached in memory for, per identifier. e.g. 5m. 0s disables the cache",
+		EnvVar: "LOOKUP_CACHE_TTL",
+	})
+	lookupCacheMaxEntries := app.Int(cli.IntOpt{
+		Name:   "lookup-cache-max-entries",
+		Value:  10000,
+		Desc:   "Maximum number of identifiers held in the in-memory lookup cache",
+		EnvVar: "LOOKUP_CACHE_MAX_ENTRIES",
+	})
+	maxBatchSize := app.Int(cli.IntOpt{
+		Name:   "max-batch-size",
+		Value:  1000,
+		Desc:   "Maximum number of identifiers accepted by a single /concordances request",
+		EnvVar: "MAX_BATCH_SIZE",
+	})
+	exportRateLimit := app.Int(cli.IntOpt{
+		Name:   "export-rate-limit",
+		Value:  5,
+		Desc:   "Maximum number of /concordances/export queries per second across all exports, each exporting up to 1000 identifiers. 0 disables the limit",
+		EnvVar: "EXPORT_RATE_LIMIT",
+	})
+	otlpEndpoint := app.String(cli.StringOpt{
+		Name:   "otlp-endpoint",
+		Value:  "",
+		Desc:   "URL of the OpenTelemetry collector the spans of requests and Neo4j queries are exported to over OTLP/HTTP, e.g. http://localhost:4318. Empty disables the export",
+		EnvVar: "OTEL_EXPORTER_OTLP_ENDPOINT",
+	})
+	traceSampleRatio := app.String(cli.StringOpt{
+		Name:   "trace-sample-ratio",
+		Value:  "1",
+		Desc:   "Ratio of the traces started by the service that are sampled, traces started by callers are sampled as they decided",
+		EnvVar: "TRACE_SAMPLE_RATIO",
+	})
 	logLevel := app.String(cli.StringOpt{
 		Name:   "logLevel",
 		Value:  "info",
@@ -82,31 +164,95 @@ func main() {
 
 	log := logger.NewUPPLogger(*appSystemCode, *logLevel)
 	log.WithFields(map[string]interface{}{
-		"CACHE_DURATION": *cacheDuration,
-		"NEO_URL":        *neoURL,
-		"LOG_LEVEL":      *logLevel,
-		"PORT":           *port,
+		"CACHE_DURATION":           *cacheDuration,
+		"QUERY_TIMEOUT":            *queryTimeout,
+		"LOOKUP_CACHE_TTL":         *lookupCacheTTL,
+		"LOOKUP_CACHE_MAX_ENTRIES": *lookupCacheMaxEntries,
+		"MAX_BATCH_SIZE":           *maxBatchSize,
+		"EXPORT_RATE_LIMIT":        *exportRateLimit,
+		"BACKEND":                  *backend,
+		"SNAPSHOT_FILE":            *snapshotFile,
+		"NEO_URL":                  *neoURL,
+		"OTLP_ENDPOINT":            *otlpEndpoint,
+		"TRACE_SAMPLE_RATIO":       *traceSampleRatio,
+		"LOG_LEVEL":                *logLevel,
+		"PORT":                     *port,
 	}).Info("Starting app with arguments")
 
 	app.Action = func() {
+		if err := concordances.CheckAuthorities(); err != nil {
+			log.WithError(err).Fatal("Failed to resolve the authorities from the ontology")
+		}
 		cacheControlHeader, err := parseCacheDurationArg(*cacheDuration)
 		if err != nil {
 			log.WithError(err).Fatalf("Application failed to start")
 		}
-		dbLog := logger.NewUPPLogger(serviceName+"-cmneo4j-driver", *dbDriverLogLevel)
-		driver, err := cmneo4j.NewDefaultDriver(*neoURL, dbLog)
+		timeout, err := time.ParseDuration(*queryTimeout)
 		if err != nil {
-			log.WithError(err).Fatal("Unable to create a new cmneo4j driver")
+			log.WithError(err).Fatalf("Failed to parse query timeout")
 		}
-		defer driver.Close()
-
-		concordancesDriver, err := concordances.NewCypherDriver(driver, *apiURL)
+		cacheTTL, err := time.ParseDuration(*lookupCacheTTL)
 		if err != nil {
-			log.WithError(err).Fatal("Creating CypherDriver")
+			log.WithError(err).Fatalf("Failed to parse lookup cache ttl")
+		}
+		sampleRatio, err := strconv.ParseFloat(*traceSampleRatio, 64)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to parse trace sample ratio")
+		}
+		shutdownTracing, err := setUpTracing(*appSystemCode, *otlpEndpoint, sampleRatio)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to set up tracing")
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.WithError(err).Error("Failed to export the remaining spans")
+			}
+		}()
+
+		var concordancesDriver concordances.Driver
+		var healthChecks []fthealth.Check
+		switch *backend {
+		case "neo4j":
+			cypherDriver, closeDriver := connectCypherDriver(log, *neoURL, *dbDriverLogLevel, *apiURL, timeout)
+			defer closeDriver()
+			concordancesDriver = cypherDriver
+			healthChecks = append(healthChecks, concordances.Neo4jConnectivityCheck(cypherDriver))
+		case "snapshot":
+			reloadInterval, err := time.ParseDuration(*snapshotReloadInterval)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to parse snapshot reload interval")
+			}
+			maxAge, err := time.ParseDuration(*snapshotMaxAge)
+			if err != nil {
+				log.WithError(err).Fatal("Failed to parse snapshot max age")
+			}
+			snapshotDriver, err := concordances.NewSnapshotDriver(*apiURL, *snapshotFile)
+			if err != nil {
+				log.WithError(err).Fatal("Loading concordance snapshot")
+			}
+			ctx, stopWatching := context.WithCancel(context.Background())
+			defer stopWatching()
+			go snapshotDriver.Watch(ctx, reloadInterval, log)
+
+			concordancesDriver = snapshotDriver
+			healthChecks = append(healthChecks, snapshotDriver.AgeCheck(maxAge), snapshotDriver.ValidityCheck())
+		case "memory":
+			concordancesDriver, err = concordances.LoadMemoryDriver(*apiURL, *fixturesDir)
+			if err != nil {
+				log.WithError(err).Fatal("Loading concept fixtures")
+			}
+		default:
+			log.Fatalf("Unknown backend %q, it must be neo4j, snapshot or memory", *backend)
 		}
 
-		hh := concordances.NewHTTPHandler(log, concordancesDriver, cacheControlHeader)
-		router := registerEndpoints(hh, log, apiYml)
+		if cacheTTL > 0 {
+			concordancesDriver = concordances.NewCachingDriver(concordancesDriver, cacheTTL, *lookupCacheMaxEntries, metrics.DefaultRegistry)
+		}
+
+		hh := concordances.NewHTTPHandler(log, concordancesDriver, cacheControlHeader, *maxBatchSize, *exportRateLimit)
+		router := registerEndpoints(hh, log, apiYml, healthChecks)
 		srv := newHTTPServer(*port, router)
 		go startHTTPServer(srv, log)
 		log.Infof("service will listen on port: %s", *port)
@@ -114,19 +260,168 @@ func main() {
 		stopHTTPServer(srv, log)
 	}
 
+	app.Command("snapshot", "Concordance snapshots, which the snapshot backend serves", func(snapshot *cli.Cmd) {
+		snapshot.Command("export", "Exports every canonical concept of Neo4j to a snapshot file", func(export *cli.Cmd) {
+			output := export.String(cli.StringOpt{
+				Name:  "output o",
+				Value: "./concordances.snapshot.gz",
+				Desc:  "Snapshot file written, it is only replaced once the export is complete",
+			})
+			pageSize := export.Int(cli.IntOpt{
+				Name:  "page-size",
+				Value: 500,
+				Desc:  "Number of canonical concepts read from Neo4j at a time",
+			})
+			restart := export.Bool(cli.BoolOpt{
+				Name:  "restart",
+				Value: false,
+				Desc:  "Start the export over instead of resuming an interrupted one",
+			})
+
+			export.Action = func() {
+				timeout, err := time.ParseDuration(*queryTimeout)
+				if err != nil {
+					log.WithError(err).Fatalf("Failed to parse query timeout")
+				}
+				cypherDriver, closeDriver := connectCypherDriver(log, *neoURL, *dbDriverLogLevel, *apiURL, timeout)
+				defer closeDriver()
+
+				ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+				defer stop()
+				err = concordances.ExportSnapshot(ctx, cypherDriver, *output, redactedURL(*neoURL), *pageSize, *restart, log)
+				if err != nil {
+					log.WithError(err).Fatal("Snapshot export failed, run it again to resume it")
+				}
+				log.WithField("file", *output).Info("Snapshot exported")
+			}
+		})
+
+		snapshot.Command("diff", "Reports the identifiers added, removed and re-pointed to other concepts in TARGET compared with BASE, per authority", func(diff *cli.Cmd) {
+			diff.Spec = "[--format] [--page-size] BASE TARGET"
+			format := diff.String(cli.StringOpt{
+				Name:  "format",
+				Value: "text",
+				Desc:  "Output format: text or json",
+			})
+			pageSize := diff.Int(cli.IntOpt{
+				Name:  "page-size",
+				Value: 500,
+				Desc:  "Number of canonical concepts read from Neo4j at a time",
+			})
+			base := diff.String(cli.StringArg{
+				Name: "BASE",
+				Desc: "Snapshot file, or URL of a Neo4j instance, compared against",
+			})
+			target := diff.String(cli.StringArg{
+				Name: "TARGET",
+				Desc: "Snapshot file, or URL of a Neo4j instance, compared with BASE",
+			})
+
+			diff.Action = func() {
+				if *format != "text" && *format != "json" {
+					log.Fatalf("Unknown format %q, it must be text or json", *format)
+				}
+				timeout, err := time.ParseDuration(*queryTimeout)
+				if err != nil {
+					log.WithError(err).Fatalf("Failed to parse query timeout")
+				}
+				ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+				defer stop()
+
+				var records [2][]concordances.SnapshotRecord
+				for i, location := range []string{*base, *target} {
+					records[i], err = readSnapshotRecords(ctx, log, location, *dbDriverLogLevel, *apiURL, timeout, *pageSize)
+					if err != nil {
+						log.WithError(err).WithField("snapshot", location).Fatal("Reading snapshot")
+					}
+				}
+				authorities, err := concordances.DiffSnapshots(records[0], records[1], *apiURL)
+				if err != nil {
+					log.WithError(err).Fatal("Comparing snapshots")
+				}
+
+				result := concordances.SnapshotDiff{Base: displayedLocation(*base), Target: displayedLocation(*target), Authorities: authorities}
+				if *format == "json" {
+					encoder := json.NewEncoder(os.Stdout)
+					encoder.SetIndent("", "  ")
+					err = encoder.Encode(result)
+				} else {
+					err = concordances.WriteSnapshotDiff(os.Stdout, result)
+				}
+				if err != nil {
+					log.WithError(err).Fatal("Writing snapshot diff")
+				}
+			}
+		})
+	})
+
 	app.Run(os.Args)
 }
 
-func registerEndpoints(hh *concordances.HTTPHandler, log *logger.UPPLogger, apiYml *string) http.Handler {
+// connectCypherDriver connects to Neo4j, closeDriver closes the connection
+func connectCypherDriver(log *logger.UPPLogger, neoURL string, dbDriverLogLevel string, apiURL string, queryTimeout time.Duration) (cypherDriver concordances.CypherDriver, closeDriver func()) {
+	dbLog := logger.NewUPPLogger(serviceName+"-cmneo4j-driver", dbDriverLogLevel)
+	driver, err := cmneo4j.NewDefaultDriver(neoURL, dbLog)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to create a new cmneo4j driver")
+	}
+
+	cypherDriver, err = concordances.NewCypherDriver(driver, apiURL, queryTimeout, metrics.DefaultRegistry)
+	if err != nil {
+		log.WithError(err).Fatal("Creating CypherDriver")
+	}
+	return cypherDriver, func() { driver.Close() }
+}
+
+// readSnapshotRecords reads the records of a snapshot file, or of every canonical concept of a Neo4j instance
+// when the location is a URL
+func readSnapshotRecords(ctx context.Context, log *logger.UPPLogger, location string, dbDriverLogLevel string, apiURL string, queryTimeout time.Duration, pageSize int) ([]concordances.SnapshotRecord, error) {
+	if strings.Contains(location, "://") {
+		cypherDriver, closeDriver := connectCypherDriver(log, location, dbDriverLogLevel, apiURL, queryTimeout)
+		defer closeDriver()
+		return concordances.CollectSnapshot(ctx, cypherDriver, pageSize)
+	}
+
+	f, err := os.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, records, err := concordances.ReadSnapshot(f)
+	return records, err
+}
+
+// displayedLocation is the location of a snapshot without the password of a Neo4j URL
+func displayedLocation(location string) string {
+	if strings.Contains(location, "://") {
+		return redactedURL(location)
+	}
+	return location
+}
+
+// redactedURL hides the password of the URL, it is empty if the URL cannot be parsed
+func redactedURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+func registerEndpoints(hh *concordances.HTTPHandler, log *logger.UPPLogger, apiYml *string, healthChecks []fthealth.Check) http.Handler {
 	servicesRouter := mux.NewRouter()
 	mh := &handlers.MethodHandler{
-		"GET": http.HandlerFunc(hh.GetConcordances),
+		"GET":  http.HandlerFunc(hh.GetConcordances),
+		"POST": http.HandlerFunc(hh.PostConcordances),
 	}
 	servicesRouter.Handle("/concordances", mh)
+	servicesRouter.HandleFunc("/concordances/translate", hh.Translate).Methods("GET")
+	servicesRouter.HandleFunc("/concordances/export", hh.Export).Methods("GET")
 
 	var monitoringRouter http.Handler = servicesRouter
 	monitoringRouter = httphandlers.TransactionAwareRequestLoggingHandler(log, monitoringRouter)
 	monitoringRouter = httphandlers.HTTPMetricsHandler(metrics.DefaultRegistry, monitoringRouter)
+	monitoringRouter = concordances.TracingHandler(monitoringRouter)
 
 	// The top one of these feels more correct, but the lower one matches what we have in Dropwizard,
 	// so it's what apps expect currently same as ping, the content of build-info needs more definition
@@ -136,7 +431,8 @@ func registerEndpoints(hh *concordances.HTTPHandler, log *logger.UPPLogger, apiY
 	router.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
 
 	router.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(hh.GTG))
-	router.HandleFunc("/__health", fthealth.Handler(hh.HealthCheck(serviceName)))
+	router.HandleFunc("/__health", fthealth.Handler(hh.HealthCheck(serviceName, healthChecks...)))
+	router.Handle("/__metrics", exp.ExpHandler(metrics.DefaultRegistry))
 
 	router.Handle("/", monitoringRouter)
 	if apiYml != nil {
@@ -151,6 +447,27 @@ func registerEndpoints(hh *concordances.HTTPHandler, log *logger.UPPLogger, apiY
 	return router
 }
 
+// setUpTracing propagates W3C traceparent headers and exports spans to the OTLP/HTTP endpoint, spans are not
+// recorded when there is none. shutdown exports the spans not exported yet.
+func setUpTracing(serviceName string, endpoint string, sampleRatio float64) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
 func newHTTPServer(port string, router http.Handler) *http.Server {
 	return &http.Server{
 		Addr:    ":" + port,